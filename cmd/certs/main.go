@@ -6,11 +6,15 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"flag"
 	"metrix/pkg/crypto"
 	"metrix/pkg/logger"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -59,7 +63,65 @@ func createPublicKey(publicKeyPath string, privateKey *rsa.PrivateKey) (*rsa.Pub
 	return &privateKey.PublicKey, nil
 }
 
+// writeCertificate - the function that writes a PEM certificate and its private key,
+// the key is readable by the owner only.
+func writeCertificate(dir string, name string, certPEM []byte, keyPEM []byte) error {
+	if err := os.WriteFile(filepath.Join(dir, name+".crt"), certPEM, 0o644); err != nil {
+		return errors.Wrapf(err, "failed to write %s certificate", name)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0o600); err != nil {
+		return errors.Wrapf(err, "failed to write %s key", name)
+	}
+
+	return nil
+}
+
+// createTLSCertificates - the function that generates a local CA with server and client
+// certificates issued by it, to be used for TLS and mutual TLS in testing.
+func createTLSCertificates(dir string, hosts []string, clientCN string, validity time.Duration) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.Wrap(err, "failed to create certificates dir")
+	}
+
+	ca, err := crypto.NewCertificateAuthority("metrix local ca", validity)
+	if err != nil {
+		return errors.Wrap(err, "failed to create ca")
+	}
+
+	caKey, err := ca.KeyPEM()
+	if err != nil {
+		return errors.Wrap(err, "failed to encode ca key")
+	}
+
+	if err := writeCertificate(dir, "ca", ca.CertPEM(), caKey); err != nil {
+		return err
+	}
+
+	serverCert, serverKey, err := ca.IssueServer(hosts[0], hosts, validity)
+	if err != nil {
+		return errors.Wrap(err, "failed to issue server certificate")
+	}
+
+	if err := writeCertificate(dir, "server", serverCert, serverKey); err != nil {
+		return err
+	}
+
+	clientCert, clientKey, err := ca.IssueClient(clientCN, validity)
+	if err != nil {
+		return errors.Wrap(err, "failed to issue client certificate")
+	}
+
+	return writeCertificate(dir, "client", clientCert, clientKey)
+}
+
 func main() {
+	tlsDir := flag.String("tls-dir", "certs", "directory for generated ca, server and client certificates")
+	hosts := flag.String("hosts", "localhost,127.0.0.1,::1", "comma separated hosts of the grpc and https servers")
+	clientCN := flag.String("client-cn", "agent", "client certificate common name, used as agent id")
+	validity := flag.Duration("validity", 365*24*time.Hour, "certificates validity")
+	flag.Parse()
+
 	ctx, cancel := signal.NotifyContext(
 		context.Background(),
 		os.Interrupt,
@@ -76,4 +138,8 @@ func main() {
 	if err != nil {
 		logger.Fatal(ctx, "failed to create public key", err)
 	}
+
+	if err := createTLSCertificates(*tlsDir, strings.Split(*hosts, ","), *clientCN, *validity); err != nil {
+		logger.Fatal(ctx, "failed to create tls certificates", err)
+	}
 }
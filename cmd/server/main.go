@@ -52,7 +52,8 @@ func main() {
 	}
 
 	middlewares.SetSignKey(cfg.SignKey)
-	middlewares.InitSubnetMiddleware(cfg.TrustedSubNetDefined)
+	middlewares.InitSubnetMiddleware(cfg.TrustedNetwork)
+	cfg.WatchFile(ctx)
 
 	if err := app.Run(ctx, cfg); err != nil {
 		logger.Error(ctx, "error running http server", err)
@@ -0,0 +1,286 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"metrix/internal/model"
+	"metrix/internal/repository"
+	"metrix/pkg/logger"
+)
+
+// State - the string-based type for alert states.
+type State string
+
+// State constants for PendingState, FiringState and ResolvedState.
+// Resolved alerts are not kept by the engine, the state is only reported to notifiers.
+const (
+	PendingState  State = "pending"
+	FiringState   State = "firing"
+	ResolvedState State = "resolved"
+)
+
+// Notifier - the interface of a receiver of alert state changes: alerts that started
+// firing and firing alerts that resolved.
+type Notifier interface {
+	Notify(ctx context.Context, alerts []Alert)
+}
+
+// Alert - the structure of an active alert produced by a rule for a single series.
+// Labels combine series labels with the rule labels, Fingerprint identifies
+// the pair of rule and series.
+type Alert struct {
+	Fingerprint string            `json:"fingerprint"`
+	Rule        string            `json:"rule"`
+	ID          string            `json:"id"`
+	Labels      model.Labels      `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	State       State             `json:"state"`
+	Value       float64           `json:"value"`
+	ActiveAt    time.Time         `json:"active_at"`
+	FiredAt     *time.Time        `json:"fired_at,omitempty"`
+	ResolvedAt  *time.Time        `json:"resolved_at,omitempty"`
+}
+
+// Engine - the structure that periodically evaluates rules against the metric repository
+// and keeps pending and firing alerts.
+type Engine struct {
+	rules     []Rule
+	repo      repository.MetricRepository
+	interval  time.Duration
+	notifiers []Notifier
+
+	mux    *sync.RWMutex
+	alerts map[string]*Alert
+}
+
+// NewEngine - the builder function for Engine, rules must be compiled, see LoadRules.
+func NewEngine(
+	rules []Rule,
+	repo repository.MetricRepository,
+	interval time.Duration,
+	notifiers ...Notifier,
+) *Engine {
+	return &Engine{
+		rules:     rules,
+		repo:      repo,
+		interval:  interval,
+		notifiers: notifiers,
+		mux:       &sync.RWMutex{},
+		alerts:    make(map[string]*Alert),
+	}
+}
+
+// Run - the method that evaluates rules every interval until the context is done.
+func (e *Engine) Run(ctx context.Context) {
+	if len(e.rules) == 0 || e.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(e.interval)
+	for {
+		select {
+		case <-ticker.C:
+			if err := e.Evaluate(ctx, time.Now().UTC()); err != nil {
+				logger.Warn(ctx, fmt.Sprintf("failed to evaluate alerting rules %s", err))
+			}
+		case <-ctx.Done():
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// Alerts - the method that returns active alerts ordered by rule and series.
+func (e *Engine) Alerts() []Alert {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
+	keys := make([]string, 0, len(e.alerts))
+	for k := range e.alerts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	alerts := make([]Alert, 0, len(keys))
+	for _, k := range keys {
+		alerts = append(alerts, *e.alerts[k])
+	}
+
+	return alerts
+}
+
+// Evaluate - the method that evaluates all rules at the moment now. An alert becomes
+// pending when its condition holds, firing once it holds for the rule duration,
+// and is dropped as soon as the condition no longer holds. Notifiers are told about
+// alerts that started firing and firing alerts that resolved. A rule failed to evaluate
+// is skipped and keeps its alerts as they were.
+func (e *Engine) Evaluate(ctx context.Context, now time.Time) error {
+	metrics, err := e.readMetrics(ctx)
+	if err != nil {
+		return err
+	}
+
+	active := map[string]*Alert{}
+	skipped := map[string]bool{}
+	for i := range e.rules {
+		rule := &e.rules[i]
+		for j := range metrics {
+			metric := &metrics[j]
+			if !rule.expr.Matches(metric) {
+				continue
+			}
+
+			value, err := e.value(ctx, rule.expr, metric, now)
+			if err != nil {
+				logger.Warn(ctx, fmt.Sprintf("failed to evaluate alerting rule %s", err), "rule", rule.Name)
+				skipped[rule.Name] = true
+				break
+			}
+
+			if rule.expr.Compare(value) {
+				alert := e.newAlert(rule, metric, value)
+				active[alert.Fingerprint] = alert
+			}
+		}
+	}
+
+	changed := e.apply(active, skipped, now)
+	if len(changed) == 0 {
+		return nil
+	}
+
+	for _, n := range e.notifiers {
+		n.Notify(ctx, changed)
+	}
+
+	return nil
+}
+
+// apply - the method that replaces alerts with active ones carrying the state over,
+// alerts of skipped rules are kept. It returns alerts whose firing state changed.
+func (e *Engine) apply(active map[string]*Alert, skipped map[string]bool, now time.Time) []Alert {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	changed := []Alert{}
+	for key, alert := range active {
+		if prev, ok := e.alerts[key]; ok {
+			alert.ActiveAt, alert.FiredAt, alert.State = prev.ActiveAt, prev.FiredAt, prev.State
+		} else {
+			alert.ActiveAt = now
+		}
+
+		rule := e.rule(alert.Rule)
+		if alert.State == PendingState && now.Sub(alert.ActiveAt) >= rule.expr.For {
+			firedAt := now
+			alert.State, alert.FiredAt = FiringState, &firedAt
+			changed = append(changed, *alert)
+		}
+	}
+
+	for key, prev := range e.alerts {
+		if _, ok := active[key]; ok {
+			continue
+		}
+		if skipped[prev.Rule] {
+			active[key] = prev
+			continue
+		}
+		if prev.State != FiringState {
+			continue
+		}
+		resolved := *prev
+		resolvedAt := now
+		resolved.State, resolved.ResolvedAt = ResolvedState, &resolvedAt
+		changed = append(changed, resolved)
+	}
+	e.alerts = active
+
+	sort.Slice(changed, func(i, j int) bool { return changed[i].Fingerprint < changed[j].Fingerprint })
+
+	return changed
+}
+
+func (e *Engine) newAlert(rule *Rule, metric *model.Metric, value float64) *Alert {
+	labels := model.Labels{}
+	for k, v := range metric.Labels {
+		labels[k] = v
+	}
+	for k, v := range rule.Labels {
+		labels[k] = v
+	}
+
+	return &Alert{
+		Fingerprint: rule.Name + "/" + metric.SeriesKey(),
+		Rule:        rule.Name,
+		ID:          metric.ID,
+		Labels:      labels,
+		Annotations: rule.Annotations,
+		State:       PendingState,
+		Value:       value,
+	}
+}
+
+func (e *Engine) rule(name string) *Rule {
+	for i := range e.rules {
+		if e.rules[i].Name == name {
+			return &e.rules[i]
+		}
+	}
+
+	return nil
+}
+
+func (e *Engine) readMetrics(ctx context.Context) ([]model.Metric, error) {
+	keys, err := e.repo.ReadIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metric keys: %w", err)
+	}
+
+	if keys == nil || len(*keys) == 0 {
+		return nil, nil
+	}
+
+	metrics, err := e.repo.ReadMany(ctx, *keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metrics: %w", err)
+	}
+
+	return *metrics, nil
+}
+
+// value - the method that computes the value of the expression operand for the series:
+// the current value or, for rate, the per-second increase over the window. The rate is
+// zero when fewer than two samples were recorded within the window.
+func (e *Engine) value(
+	ctx context.Context,
+	expr *Expr,
+	metric *model.Metric,
+	now time.Time,
+) (float64, error) {
+	if expr.Func != RateFunc {
+		sample := model.NewSample(metric, now)
+		return sample.Float(), nil
+	}
+
+	samples, err := e.repo.ReadRange(ctx, metric.SeriesKey(), now.Add(-expr.Window), now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read metric history: %w", err)
+	}
+
+	if samples == nil || len(*samples) < 2 {
+		return 0, nil
+	}
+
+	first, last := (*samples)[0], (*samples)[len(*samples)-1]
+	seconds := last.Timestamp.Sub(first.Timestamp).Seconds()
+	if seconds <= 0 {
+		return 0, nil
+	}
+
+	return (last.Float() - first.Float()) / seconds, nil
+}
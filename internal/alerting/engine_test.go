@@ -0,0 +1,271 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"metrix/internal/model"
+	"metrix/internal/repository"
+	"metrix/internal/storages"
+)
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "alerts.yaml")
+	data := []byte(`rules:
+  - name: LowFreeMemory
+    expr: FreeMemory < 500MB for 2m
+    labels:
+      severity: critical
+  - name: AgentStalled
+    expr: rate(PollCount) == 0
+    for: 30s
+`)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Errorf("failed to write rules: %v", err)
+		return
+	}
+
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Errorf("LoadRules() error = %v", err)
+		return
+	}
+
+	if len(rules) != 2 {
+		t.Errorf("LoadRules() = %d rules, want 2", len(rules))
+		return
+	}
+	if rules[0].expr.For != 2*time.Minute || rules[0].Labels["severity"] != "critical" {
+		t.Errorf("LoadRules() first rule = %+v", rules[0])
+	}
+	if rules[1].expr.For != 30*time.Second || rules[1].expr.Func != RateFunc {
+		t.Errorf("LoadRules() second rule = %+v", rules[1])
+	}
+}
+
+type recorder struct {
+	alerts []Alert
+}
+
+func (r *recorder) Notify(ctx context.Context, alerts []Alert) {
+	r.alerts = append(r.alerts, alerts...)
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := storages.NewInMemoryStorage(ctx, "", 0, false, time.Hour, 1)
+
+	rule := Rule{Name: "LowFreeMemory", Expr: "FreeMemory < 500MB for 2m"}
+	if err := rule.Compile(); err != nil {
+		t.Errorf("Rule.Compile() error = %v", err)
+		return
+	}
+	notifier := &recorder{}
+	e := NewEngine([]Rule{rule}, repo, time.Second, notifier)
+
+	setFree := func(v float64) {
+		_, err := repo.UpsertMany(ctx, []model.Metric{
+			{ID: "FreeMemory", MType: model.GaugeType, Labels: model.Labels{"agent": "h1"}, Value: &v},
+		})
+		if err != nil {
+			t.Errorf("UpsertMany() error = %v", err)
+		}
+	}
+
+	start := time.Now().UTC()
+	tests := []struct {
+		name      string
+		free      float64
+		at        time.Time
+		wantState State
+	}{
+		{name: "Test 1: Condition does not hold", free: 1 << 30, at: start},
+		{name: "Test 2: Condition holds, pending", free: 100 << 20, at: start.Add(time.Minute), wantState: PendingState},
+		{name: "Test 3: Hold duration not reached", free: 100 << 20, at: start.Add(2 * time.Minute), wantState: PendingState},
+		{name: "Test 4: Hold duration reached, firing", free: 100 << 20, at: start.Add(3 * time.Minute), wantState: FiringState},
+		{name: "Test 5: Condition resolved", free: 1 << 30, at: start.Add(4 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFree(tt.free)
+			if err := e.Evaluate(ctx, tt.at); err != nil {
+				t.Errorf("Engine.Evaluate() error = %v", err)
+				return
+			}
+
+			alerts := e.Alerts()
+			if tt.wantState == "" {
+				if len(alerts) != 0 {
+					t.Errorf("Engine.Alerts() = %+v, want none", alerts)
+				}
+				return
+			}
+
+			if len(alerts) != 1 || alerts[0].State != tt.wantState {
+				t.Errorf("Engine.Alerts() = %+v, want single %s alert", alerts, tt.wantState)
+				return
+			}
+			if alerts[0].Labels["agent"] != "h1" || alerts[0].Value != 100<<20 {
+				t.Errorf("Engine.Alerts() = %+v, want series labels and value", alerts[0])
+			}
+		})
+	}
+
+	if len(notifier.alerts) != 2 ||
+		notifier.alerts[0].State != FiringState ||
+		notifier.alerts[1].State != ResolvedState {
+		t.Errorf("Notifier got %+v, want firing then resolved", notifier.alerts)
+	}
+}
+
+func TestEngine_EvaluateRate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := storages.NewInMemoryStorage(ctx, "", 0, false, time.Hour, 1)
+
+	rule := Rule{Name: "AgentStalled", Expr: "rate(PollCount[1h]) == 0"}
+	if err := rule.Compile(); err != nil {
+		t.Errorf("Rule.Compile() error = %v", err)
+		return
+	}
+	e := NewEngine([]Rule{rule}, repo, time.Second)
+
+	for _, delta := range []int64{5, 5} {
+		_, err := repo.UpsertMany(ctx, []model.Metric{
+			{ID: "PollCount", MType: model.CounterType, Delta: &delta},
+		})
+		if err != nil {
+			t.Errorf("UpsertMany() error = %v", err)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := e.Evaluate(ctx, time.Now().UTC()); err != nil {
+		t.Errorf("Engine.Evaluate() error = %v", err)
+		return
+	}
+	if alerts := e.Alerts(); len(alerts) != 1 || alerts[0].State != FiringState {
+		t.Errorf("Engine.Alerts() = %+v, want single firing alert for a flat counter", alerts)
+	}
+
+	delta := int64(7)
+	if _, err := repo.UpsertMany(ctx, []model.Metric{
+		{ID: "PollCount", MType: model.CounterType, Delta: &delta},
+	}); err != nil {
+		t.Errorf("UpsertMany() error = %v", err)
+		return
+	}
+
+	if err := e.Evaluate(ctx, time.Now().UTC()); err != nil {
+		t.Errorf("Engine.Evaluate() error = %v", err)
+		return
+	}
+	if alerts := e.Alerts(); len(alerts) != 0 {
+		t.Errorf("Engine.Alerts() = %+v, want none for a growing counter", alerts)
+	}
+}
+
+// failingHistory - the repository stub whose history reads fail once broken.
+type failingHistory struct {
+	repository.MetricRepository
+	broken bool
+}
+
+func (r *failingHistory) ReadRange(
+	ctx context.Context,
+	metricKey string,
+	from time.Time,
+	to time.Time,
+) (*[]model.Sample, error) {
+	if r.broken {
+		return nil, errors.New("history is unavailable")
+	}
+
+	return r.MetricRepository.ReadRange(ctx, metricKey, from, to)
+}
+
+func TestEngine_EvaluateSkipsFailedRule(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := &failingHistory{MetricRepository: storages.NewInMemoryStorage(ctx, "", 0, false, time.Hour, 1)}
+
+	rules := []Rule{
+		{Name: "AgentStalled", Expr: "rate(PollCount[1h]) == 0"},
+		{Name: "LowFreeMemory", Expr: "FreeMemory < 500"},
+	}
+	for i := range rules {
+		if err := rules[i].Compile(); err != nil {
+			t.Errorf("Rule.Compile() error = %v", err)
+			return
+		}
+	}
+	e := NewEngine(rules, repo, time.Second)
+
+	delta, value := int64(5), 100.0
+	for range 2 {
+		_, err := repo.UpsertMany(ctx, []model.Metric{
+			{ID: "PollCount", MType: model.CounterType, Delta: &delta},
+			{ID: "FreeMemory", MType: model.GaugeType, Value: &value},
+		})
+		if err != nil {
+			t.Errorf("UpsertMany() error = %v", err)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := e.Evaluate(ctx, time.Now().UTC()); err != nil {
+		t.Errorf("Engine.Evaluate() error = %v", err)
+		return
+	}
+
+	repo.broken = true
+	if err := e.Evaluate(ctx, time.Now().UTC()); err != nil {
+		t.Errorf("Engine.Evaluate() error = %v", err)
+		return
+	}
+
+	alerts := e.Alerts()
+	if len(alerts) != 2 || alerts[0].Rule != "AgentStalled" || alerts[1].Rule != "LowFreeMemory" {
+		t.Errorf("Engine.Alerts() = %+v, want the alert of the failed rule kept", alerts)
+	}
+}
+
+func TestCheckHistory(t *testing.T) {
+	rate := Rule{Name: "AgentStalled", Expr: "rate(PollCount) == 0"}
+	value := Rule{Name: "LowFreeMemory", Expr: "FreeMemory < 500"}
+	for _, r := range []*Rule{&rate, &value} {
+		if err := r.Compile(); err != nil {
+			t.Errorf("Rule.Compile() error = %v", err)
+			return
+		}
+	}
+
+	tests := []struct {
+		name      string
+		rules     []Rule
+		retention time.Duration
+		wantErr   bool
+	}{
+		{name: "Test 1", rules: []Rule{rate, value}, retention: time.Hour},
+		{name: "Test 2", rules: []Rule{value}},
+		{name: "Test 3", rules: []Rule{value, rate}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckHistory(tt.rules, tt.retention); (err != nil) != tt.wantErr {
+				t.Errorf("CheckHistory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
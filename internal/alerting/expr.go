@@ -0,0 +1,225 @@
+package alerting
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"metrix/internal/model"
+)
+
+// RateFunc - the function name computing per-second rate of a series over a window.
+const RateFunc = "rate"
+
+// DefaultRateWindow - the window used by rate when none is given, e.g. rate(PollCount).
+const DefaultRateWindow = time.Minute
+
+// operators - the supported comparison operators, two-char ones go first to be matched greedily.
+var operators = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// units - the supported threshold suffixes, byte units are binary.
+var units = map[string]float64{
+	"":   1,
+	"B":  1,
+	"KB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+	"TB": 1 << 40,
+}
+
+var (
+	forRe       = regexp.MustCompile(`\s+for\s+(\S+)\s*$`)
+	thresholdRe = regexp.MustCompile(`^([-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?)\s*([a-zA-Z]*)$`)
+)
+
+// Expr - the structure of a parsed rule expression, e.g. `FreeMemory{agent="h1"} < 500MB for 2m`
+// or `rate(PollCount[5m]) == 0`.
+type Expr struct {
+	Func      string
+	ID        string
+	Labels    model.Labels
+	Window    time.Duration
+	Op        string
+	Threshold float64
+	For       time.Duration
+}
+
+// ParseExpr - the function that parses a rule expression.
+func ParseExpr(raw string) (*Expr, error) {
+	expr := &Expr{}
+	src := strings.TrimSpace(raw)
+
+	if m := forRe.FindStringSubmatch(src); m != nil {
+		d, err := time.ParseDuration(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse for duration: %w", err)
+		}
+		expr.For = d
+		src = strings.TrimSpace(src[:len(src)-len(m[0])])
+	}
+
+	idx, op := findOperator(src)
+	if idx < 0 {
+		return nil, fmt.Errorf("no comparison operator in expression %q", raw)
+	}
+	expr.Op = op
+
+	if err := expr.parseOperand(strings.TrimSpace(src[:idx])); err != nil {
+		return nil, err
+	}
+
+	threshold, err := parseThreshold(strings.TrimSpace(src[idx+len(op):]))
+	if err != nil {
+		return nil, err
+	}
+	expr.Threshold = threshold
+
+	return expr, nil
+}
+
+// Compare - the method that applies the comparison operator to the value.
+func (e *Expr) Compare(value float64) bool {
+	switch e.Op {
+	case "<":
+		return value < e.Threshold
+	case "<=":
+		return value <= e.Threshold
+	case ">":
+		return value > e.Threshold
+	case ">=":
+		return value >= e.Threshold
+	case "==":
+		return value == e.Threshold
+	case "!=":
+		return value != e.Threshold
+	}
+
+	return false
+}
+
+// Matches - the method that checks whether the metric belongs to the expression selector.
+func (e *Expr) Matches(metric *model.Metric) bool {
+	return metric.ID == e.ID && metric.Labels.Matches(e.Labels)
+}
+
+func (e *Expr) parseOperand(operand string) error {
+	if strings.HasPrefix(operand, RateFunc+"(") && strings.HasSuffix(operand, ")") {
+		e.Func = RateFunc
+		e.Window = DefaultRateWindow
+		operand = strings.TrimSpace(operand[len(RateFunc)+1 : len(operand)-1])
+
+		if strings.HasSuffix(operand, "]") {
+			open := strings.LastIndex(operand, "[")
+			if open < 0 {
+				return fmt.Errorf("unbalanced window brackets in %q", operand)
+			}
+			window, err := time.ParseDuration(operand[open+1 : len(operand)-1])
+			if err != nil {
+				return fmt.Errorf("failed to parse rate window: %w", err)
+			}
+			if window <= 0 {
+				return fmt.Errorf("rate window must be positive, got %s", window)
+			}
+			e.Window = window
+			operand = strings.TrimSpace(operand[:open])
+		}
+	}
+
+	id, labels, err := parseSelector(operand)
+	if err != nil {
+		return err
+	}
+	e.ID, e.Labels = id, labels
+
+	return nil
+}
+
+// parseSelector - the function that parses `ID` or `ID{name="value",...}`.
+func parseSelector(selector string) (string, model.Labels, error) {
+	open := strings.IndexByte(selector, '{')
+	if open < 0 {
+		if selector == "" || strings.ContainsAny(selector, " \t()[]}") {
+			return "", nil, fmt.Errorf("invalid metric selector %q", selector)
+		}
+		return selector, nil, nil
+	}
+
+	id := strings.TrimSpace(selector[:open])
+	if id == "" || !strings.HasSuffix(selector, "}") {
+		return "", nil, fmt.Errorf("invalid metric selector %q", selector)
+	}
+
+	labels := model.Labels{}
+	rest := strings.TrimSpace(selector[open+1 : len(selector)-1])
+	for rest != "" {
+		name, after, ok := strings.Cut(rest, "=")
+		if !ok {
+			return "", nil, fmt.Errorf("invalid label matcher in %q", selector)
+		}
+
+		quoted, err := strconv.QuotedPrefix(strings.TrimSpace(after))
+		if err != nil {
+			return "", nil, fmt.Errorf("label value must be quoted in %q", selector)
+		}
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to unquote label value: %w", err)
+		}
+		labels[strings.TrimSpace(name)] = value
+
+		rest = strings.TrimSpace(strings.TrimSpace(after)[len(quoted):])
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, ","))
+	}
+
+	if err := labels.Validate(); err != nil {
+		return "", nil, fmt.Errorf("invalid metric selector: %w", err)
+	}
+
+	return id, labels, nil
+}
+
+// findOperator - the function that finds the first comparison operator outside of quotes.
+func findOperator(src string) (int, string) {
+	quoted := false
+	for i := 0; i < len(src); i++ {
+		switch {
+		case src[i] == '\\' && quoted:
+			i++
+			continue
+		case src[i] == '"':
+			quoted = !quoted
+			continue
+		case quoted:
+			continue
+		}
+
+		for _, op := range operators {
+			if strings.HasPrefix(src[i:], op) {
+				return i, op
+			}
+		}
+	}
+
+	return -1, ""
+}
+
+func parseThreshold(raw string) (float64, error) {
+	m := thresholdRe.FindStringSubmatch(raw)
+	if m == nil {
+		return 0, fmt.Errorf("invalid threshold %q", raw)
+	}
+
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse threshold: %w", err)
+	}
+
+	multiplier, ok := units[strings.ToUpper(m[2])]
+	if !ok {
+		return 0, fmt.Errorf("unknown threshold unit %q", m[2])
+	}
+
+	return value * multiplier, nil
+}
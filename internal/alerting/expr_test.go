@@ -0,0 +1,67 @@
+package alerting
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"metrix/internal/model"
+)
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    *Expr
+		wantErr bool
+	}{
+		{
+			name: "Test 1: Threshold with unit and hold duration",
+			raw:  "FreeMemory < 500MB for 2m",
+			want: &Expr{ID: "FreeMemory", Op: "<", Threshold: 500 << 20, For: 2 * time.Minute},
+		},
+		{
+			name: "Test 2: Rate with default window",
+			raw:  "rate(PollCount) == 0",
+			want: &Expr{Func: RateFunc, ID: "PollCount", Window: DefaultRateWindow, Op: "=="},
+		},
+		{
+			name: "Test 3: Rate with window and labels",
+			raw:  `rate(PollCount{agent="h1"}[5m]) <= 0.5`,
+			want: &Expr{
+				Func:      RateFunc,
+				ID:        "PollCount",
+				Labels:    model.Labels{"agent": "h1"},
+				Window:    5 * time.Minute,
+				Op:        "<=",
+				Threshold: 0.5,
+			},
+		},
+		{
+			name: "Test 4: Label value with operator characters",
+			raw:  `Load{dc="eu<1", host="a"} != -1e3`,
+			want: &Expr{
+				ID:        "Load",
+				Labels:    model.Labels{"dc": "eu<1", "host": "a"},
+				Op:        "!=",
+				Threshold: -1000,
+			},
+		},
+		{name: "Test 5: Missing operator", raw: "FreeMemory 500", wantErr: true},
+		{name: "Test 6: Unknown unit", raw: "FreeMemory < 5XB", wantErr: true},
+		{name: "Test 7: Unquoted label value", raw: "FreeMemory{host=h1} < 5", wantErr: true},
+		{name: "Test 8: Malformed hold duration", raw: "FreeMemory < 5 for ever", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExpr(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseExpr() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseExpr() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
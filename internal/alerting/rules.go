@@ -0,0 +1,97 @@
+// Module "alerting" evaluates threshold rules against stored metrics and keeps alerts state.
+package alerting
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule - the structure of an alerting rule. The hold duration may be given either
+// in the expression, e.g. `FreeMemory < 500MB for 2m`, or by the For field.
+type Rule struct {
+	Name        string            `yaml:"name"`
+	Expr        string            `yaml:"expr"`
+	For         time.Duration     `yaml:"for"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+
+	expr *Expr
+}
+
+// Compile - the method that parses and checks the rule expression.
+func (r *Rule) Compile() error {
+	if r.Name == "" {
+		return errors.New("rule name is missing")
+	}
+
+	expr, err := ParseExpr(r.Expr)
+	if err != nil {
+		return fmt.Errorf("failed to parse rule %q: %w", r.Name, err)
+	}
+
+	if r.For != 0 {
+		expr.For = r.For
+	}
+	if expr.For < 0 {
+		return fmt.Errorf("rule %q hold duration must not be negative", r.Name)
+	}
+	r.expr = expr
+
+	return nil
+}
+
+// LoadRules - the function that reads alerting rules from a YAML file, e.g.
+//
+//	rules:
+//	  - name: LowFreeMemory
+//	    expr: FreeMemory < 500MB for 2m
+//	    labels:
+//	      severity: critical
+func LoadRules(filePath string) ([]Rule, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read rules file")
+	}
+
+	file := struct {
+		Rules []Rule `yaml:"rules"`
+	}{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal rules")
+	}
+
+	rules := file.Rules
+
+	names := map[string]bool{}
+	for i := range rules {
+		if err := rules[i].Compile(); err != nil {
+			return nil, err
+		}
+		if names[rules[i].Name] {
+			return nil, fmt.Errorf("duplicate rule name %q", rules[i].Name)
+		}
+		names[rules[i].Name] = true
+	}
+
+	return rules, nil
+}
+
+// CheckHistory - the function that checks the rules can be evaluated with the metrics history
+// kept for the retention period, rate rules can not be evaluated without the history.
+func CheckHistory(rules []Rule, retention time.Duration) error {
+	if retention > 0 {
+		return nil
+	}
+
+	for i := range rules {
+		if rules[i].expr != nil && rules[i].expr.Func == RateFunc {
+			return fmt.Errorf("rule %q uses %s while metrics history is disabled", rules[i].Name, RateFunc)
+		}
+	}
+
+	return nil
+}
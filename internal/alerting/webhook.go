@@ -0,0 +1,216 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"metrix/pkg/logger"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// WebhookPayload - the structure of a webhook notification, alerts of a single rule
+// that changed state since the previous notification of the group.
+type WebhookPayload struct {
+	Group  string  `json:"group"`
+	Status State   `json:"status"`
+	Alerts []Alert `json:"alerts"`
+}
+
+// WebhookNotifier - the structure that posts alert state changes to webhook URLs.
+// Changes are grouped by rule and flushed once per interval, only the latest state of
+// an alert within the interval is considered and it is sent only if it differs from the
+// state notified before, so an alert flapping within the interval produces no noise.
+type WebhookNotifier struct {
+	client   *resty.Client
+	urls     []string
+	interval time.Duration
+
+	mux     *sync.Mutex
+	pending map[string]map[string]Alert
+	sent    map[string]State
+}
+
+// NewWebhookNotifier - the builder function for WebhookNotifier. Failed deliveries are
+// retried with exponential backoff between retryWaitTime and retryMaxWaitTime.
+func NewWebhookNotifier(
+	urls []string,
+	interval time.Duration,
+	retryCount int,
+	retryWaitTime time.Duration,
+	retryMaxWaitTime time.Duration,
+) *WebhookNotifier {
+	client := resty.New().
+		SetHeader("Content-Type", "application/json").
+		SetRetryCount(retryCount).
+		SetRetryWaitTime(retryWaitTime).
+		SetRetryMaxWaitTime(retryMaxWaitTime).
+		AddRetryCondition(func(r *resty.Response, err error) bool {
+			return err != nil ||
+				r.StatusCode() == http.StatusTooManyRequests ||
+				r.StatusCode() >= http.StatusInternalServerError
+		})
+
+	return &WebhookNotifier{
+		client:   client,
+		urls:     urls,
+		interval: interval,
+		mux:      &sync.Mutex{},
+		pending:  make(map[string]map[string]Alert),
+		sent:     make(map[string]State),
+	}
+}
+
+// Notify - the method to match Notifier interface, changes are queued until the next flush.
+func (n *WebhookNotifier) Notify(ctx context.Context, alerts []Alert) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	for _, alert := range alerts {
+		group, ok := n.pending[alert.Rule]
+		if !ok {
+			group = make(map[string]Alert)
+			n.pending[alert.Rule] = group
+		}
+		group[alert.Fingerprint] = alert
+	}
+}
+
+// shutdownFlushTimeout - the time given to the final flush of queued changes on shutdown.
+const shutdownFlushTimeout = 5 * time.Second
+
+// Run - the method that flushes queued changes every interval until the context is done,
+// changes queued by then are flushed once more within shutdownFlushTimeout.
+func (n *WebhookNotifier) Run(ctx context.Context) {
+	if len(n.urls) == 0 || n.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(n.interval)
+	for {
+		select {
+		case <-ticker.C:
+			n.Flush(ctx)
+		case <-ctx.Done():
+			ticker.Stop()
+
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownFlushTimeout)
+			n.Flush(flushCtx)
+			cancel()
+			return
+		}
+	}
+}
+
+// Flush - the method that sends a notification per group of queued changes. A group is
+// marked as notified once any URL accepted it, otherwise it is queued again.
+func (n *WebhookNotifier) Flush(ctx context.Context) {
+	for _, payload := range n.collect() {
+		delivered := false
+		for _, url := range n.urls {
+			if err := n.send(ctx, url, payload); err != nil {
+				logger.Warn(ctx, fmt.Sprintf("failed to notify webhook %s", err))
+				continue
+			}
+			delivered = true
+		}
+
+		if delivered {
+			n.markSent(payload)
+		} else {
+			n.requeue(payload)
+		}
+	}
+}
+
+// collect - the method that drains queued changes into payloads skipping alerts
+// whose state has already been notified.
+func (n *WebhookNotifier) collect() []WebhookPayload {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	groups := make([]string, 0, len(n.pending))
+	for group := range n.pending {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	payloads := []WebhookPayload{}
+	for _, group := range groups {
+		payload := WebhookPayload{Group: group, Status: ResolvedState, Alerts: []Alert{}}
+		for fingerprint, alert := range n.pending[group] {
+			sent, ok := n.sent[fingerprint]
+			if sent == alert.State || (!ok && alert.State == ResolvedState) {
+				continue
+			}
+
+			if alert.State != ResolvedState {
+				payload.Status = FiringState
+			}
+			payload.Alerts = append(payload.Alerts, alert)
+		}
+		delete(n.pending, group)
+
+		if len(payload.Alerts) == 0 {
+			continue
+		}
+		sort.Slice(payload.Alerts, func(i, j int) bool {
+			return payload.Alerts[i].Fingerprint < payload.Alerts[j].Fingerprint
+		})
+		payloads = append(payloads, payload)
+	}
+
+	return payloads
+}
+
+// markSent - the method that remembers the notified states of the payload alerts.
+func (n *WebhookNotifier) markSent(payload WebhookPayload) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	for _, alert := range payload.Alerts {
+		if alert.State == ResolvedState {
+			delete(n.sent, alert.Fingerprint)
+		} else {
+			n.sent[alert.Fingerprint] = alert.State
+		}
+	}
+}
+
+// requeue - the method that queues undelivered alerts again for the next flush,
+// changes queued since the collection take precedence.
+func (n *WebhookNotifier) requeue(payload WebhookPayload) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+
+	group, ok := n.pending[payload.Group]
+	if !ok {
+		group = make(map[string]Alert)
+		n.pending[payload.Group] = group
+	}
+	for _, alert := range payload.Alerts {
+		if _, ok := group[alert.Fingerprint]; !ok {
+			group[alert.Fingerprint] = alert
+		}
+	}
+}
+
+func (n *WebhookNotifier) send(ctx context.Context, url string, payload WebhookPayload) error {
+	resp, err := n.client.R().
+		SetContext(ctx).
+		SetBody(payload).
+		Post(url)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("failed to post notification: status=%s body=%s", resp.Status(), resp.Body())
+	}
+
+	return nil
+}
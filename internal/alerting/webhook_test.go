@@ -0,0 +1,194 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier_Flush(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		mux      sync.Mutex
+		attempts int
+		payloads []WebhookPayload
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		payload := WebhookPayload{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		payloads = append(payloads, payload)
+	}))
+	defer srv.Close()
+
+	n := NewWebhookNotifier([]string{srv.URL}, time.Second, 2, time.Millisecond, 10*time.Millisecond)
+
+	firing := func(rule, fingerprint string) Alert {
+		return Alert{Rule: rule, Fingerprint: fingerprint, State: FiringState}
+	}
+	resolved := func(rule, fingerprint string) Alert {
+		return Alert{Rule: rule, Fingerprint: fingerprint, State: ResolvedState}
+	}
+
+	tests := []struct {
+		name       string
+		notify     [][]Alert
+		wantGroups map[string]int
+		wantStatus map[string]State
+	}{
+		{
+			name: "Test 1: Alerts of a rule are grouped, delivery retried",
+			notify: [][]Alert{
+				{firing("LowFreeMemory", "LowFreeMemory/a"), firing("LowFreeMemory", "LowFreeMemory/b")},
+				{firing("HighLoad", "HighLoad/a")},
+			},
+			wantGroups: map[string]int{"LowFreeMemory": 2, "HighLoad": 1},
+			wantStatus: map[string]State{"LowFreeMemory": FiringState, "HighLoad": FiringState},
+		},
+		{
+			name: "Test 2: Flapping within interval is suppressed",
+			notify: [][]Alert{
+				{resolved("LowFreeMemory", "LowFreeMemory/a")},
+				{firing("LowFreeMemory", "LowFreeMemory/a")},
+				{firing("HighLoad", "HighLoad/a")},
+			},
+			wantGroups: map[string]int{},
+		},
+		{
+			name: "Test 3: Resolution is sent once",
+			notify: [][]Alert{
+				{resolved("HighLoad", "HighLoad/a")},
+				{resolved("HighLoad", "HighLoad/a")},
+			},
+			wantGroups: map[string]int{"HighLoad": 1},
+			wantStatus: map[string]State{"HighLoad": ResolvedState},
+		},
+		{
+			name: "Test 4: Never fired alert is not resolved",
+			notify: [][]Alert{
+				{resolved("Unknown", "Unknown/a")},
+			},
+			wantGroups: map[string]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux.Lock()
+			payloads = nil
+			mux.Unlock()
+
+			for _, alerts := range tt.notify {
+				n.Notify(ctx, alerts)
+			}
+			n.Flush(ctx)
+
+			mux.Lock()
+			defer mux.Unlock()
+
+			if len(payloads) != len(tt.wantGroups) {
+				t.Errorf("WebhookNotifier.Flush() sent %d payloads, want %d", len(payloads), len(tt.wantGroups))
+				return
+			}
+			for _, payload := range payloads {
+				if len(payload.Alerts) != tt.wantGroups[payload.Group] {
+					t.Errorf("group %s has %d alerts, want %d", payload.Group, len(payload.Alerts), tt.wantGroups[payload.Group])
+				}
+				if payload.Status != tt.wantStatus[payload.Group] {
+					t.Errorf("group %s status = %s, want %s", payload.Group, payload.Status, tt.wantStatus[payload.Group])
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_FlushRequeue(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		mux       sync.Mutex
+		available bool
+		payloads  []WebhookPayload
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		payload := WebhookPayload{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		payloads = append(payloads, payload)
+	}))
+	defer srv.Close()
+
+	n := NewWebhookNotifier([]string{srv.URL}, time.Second, 0, time.Millisecond, time.Millisecond)
+	n.Notify(ctx, []Alert{{Rule: "HighLoad", Fingerprint: "HighLoad/a", State: FiringState}})
+	n.Flush(ctx)
+
+	mux.Lock()
+	available = true
+	mux.Unlock()
+	n.Flush(ctx)
+
+	mux.Lock()
+	defer mux.Unlock()
+	if len(payloads) != 1 || len(payloads[0].Alerts) != 1 || payloads[0].Status != FiringState {
+		t.Errorf("WebhookNotifier.Flush() sent %+v, want the undelivered alert once", payloads)
+	}
+}
+
+func TestWebhookNotifier_RunFlushesOnShutdown(t *testing.T) {
+	received := make(chan WebhookPayload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := WebhookPayload{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- payload
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := NewWebhookNotifier([]string{srv.URL}, time.Hour, 0, time.Millisecond, time.Millisecond)
+	n.Notify(ctx, []Alert{{Rule: "HighLoad", Fingerprint: "HighLoad/a", State: FiringState}})
+
+	done := make(chan struct{})
+	go func() {
+		n.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	select {
+	case payload := <-received:
+		if payload.Group != "HighLoad" {
+			t.Errorf("WebhookNotifier.Run() sent group %s, want HighLoad", payload.Group)
+		}
+	default:
+		t.Errorf("WebhookNotifier.Run() did not flush queued alerts on shutdown")
+	}
+}
@@ -3,11 +3,14 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"metrix/internal/alerting"
 	"metrix/internal/bootstrap"
 	"metrix/internal/closer"
 	"metrix/internal/config"
@@ -15,9 +18,11 @@ import (
 	"metrix/internal/handlers"
 	"metrix/internal/http"
 	"metrix/internal/repository"
+	"metrix/pkg/crypto"
 	"metrix/pkg/logger"
 
 	"github.com/jmoiron/sqlx"
+	bolt "go.etcd.io/bbolt"
 )
 
 // Run - launches web-server for metrics aggregation.
@@ -33,22 +38,96 @@ func Run(ctx context.Context, cfg *config.Config) (err error) {
 		}
 	}
 
+	var kv *bolt.DB
+	if cfg.BoltPath != "" {
+		kv, err = bootstrap.InitBolt(ctx, cfg.BoltPath)
+		if err != nil {
+			logger.Fatal(ctx, "failed to init bolt db", err)
+		}
+	}
+
 	repoGroup := repository.NewGroup(
 		ctx,
 		db,
+		kv,
 		cfg.FileStoragePath,
 		cfg.StoreInterval,
 		cfg.Restore,
+		time.Duration(cfg.HistoryRetention*int64(time.Second)),
+		int(cfg.SnapshotsKept),
 	)
 
-	// HTTP server
+	// Alerting
+	rules := []alerting.Rule{}
+	if cfg.AlertRulesFile != "" {
+		rules, err = alerting.LoadRules(cfg.AlertRulesFile)
+		if err != nil {
+			logger.Fatal(ctx, "failed to load alerting rules", err)
+		}
+
+		err = alerting.CheckHistory(rules, time.Duration(cfg.HistoryRetention*int64(time.Second)))
+		if err != nil {
+			logger.Fatal(ctx, "failed to check alerting rules", err)
+		}
+	}
+
+	notifiers := []alerting.Notifier{}
+	if len(cfg.WebhookURLs) > 0 {
+		webhook := alerting.NewWebhookNotifier(
+			cfg.WebhookURLs,
+			time.Duration(cfg.WebhookGroupInterval*int64(time.Second)),
+			int(cfg.WebhookRetryCount),
+			cfg.WebhookRetryWait,
+			cfg.WebhookRetryMaxWait,
+		)
+		go webhook.Run(ctx)
+		notifiers = append(notifiers, webhook)
+	}
+
+	alertEngine := alerting.NewEngine(
+		rules,
+		repoGroup.MetricRepo,
+		time.Duration(cfg.AlertEvalInterval*int64(time.Second)),
+		notifiers...,
+	)
+	go alertEngine.Run(ctx)
+
+	// GRPC Server
+	var grpcTLS *tls.Config
+	if cfg.GRPCTLSCert != "" {
+		grpcTLS, err = crypto.ServerTLSConfig(cfg.GRPCTLSCert, cfg.GRPCTLSKey, cfg.GRPCClientCA)
+		if err != nil {
+			logger.Fatal(ctx, "failed to load grpc tls config", err)
+		}
+	}
+
 	healthHandlers := handlers.NewHealthHandlers(repoGroup)
+
+	gs := grpcservice.NewGServiceServer(
+		repoGroup.MetricRepo,
+		repoGroup.AgentRepo,
+		repoGroup.Updates,
+		healthHandlers.Controller(),
+	)
+	gs.Start(ctx, cfg.GRPCAddress, cfg.TrustedNetwork, grpcTLS, cfg.SignKey)
+
+	gateway, err := gs.Gateway(ctx)
+	if err != nil {
+		logger.Fatal(ctx, "failed to build rest gateway", err)
+	}
+
+	// HTTP server
 	metricsHandlers := handlers.NewMetricsHandlers(repoGroup)
+	agentsHandlers := handlers.NewAgentsHandlers(repoGroup)
+	alertsHandlers := handlers.NewAlertsHandlers(alertEngine)
 
 	httpServer := http.New(
 		cfg,
 		healthHandlers,
 		metricsHandlers,
+		agentsHandlers,
+		alertsHandlers,
+		gateway,
 	)
 
 	httpServer.Start(ctx)
@@ -56,10 +135,6 @@ func Run(ctx context.Context, cfg *config.Config) (err error) {
 	healthHandlers.SetLiveness(true)
 	healthHandlers.SetReadiness(true)
 
-	// GRPC Server
-	gs := grpcservice.NewGServiceServer(repoGroup.MetricRepo)
-	gs.Start(ctx, cfg.GRPCAddress, cfg.TrustedSubNetDefined)
-
 	gracefulShutDown(ctx, cancel)
 
 	return nil
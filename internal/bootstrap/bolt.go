@@ -0,0 +1,29 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"metrix/internal/closer"
+	"metrix/pkg/logger"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltOpenTimeout - the time to wait for the lock of the database file held by another process.
+const boltOpenTimeout = 5 * time.Second
+
+// InitBolt - function that opens the embedded database file, it is created when missing.
+func InitBolt(ctx context.Context, path string) (*bolt.DB, error) {
+	logger.Info(ctx, "initializing bolt db")
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("open bolt db error: %w", err)
+	}
+	logger.Info(ctx, "bolt db initialized")
+
+	closer.Add(db.Close)
+
+	return db, nil
+}
@@ -4,6 +4,8 @@ package bootstrap
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -14,11 +16,48 @@ import (
 	"metrix/pkg/logger"
 
 	_ "github.com/jackc/pgx/stdlib"
+	_ "modernc.org/sqlite"
 )
 
+// sqliteScheme - the DSN prefix selecting the SQLite database, the rest is the file path.
+const sqliteScheme = "sqlite://"
+
+// sqlitePragmas - the connection options: locks held by other processes are waited for
+// instead of failing, and the write-ahead journal keeps the file consistent on crashes.
+const sqlitePragmas = "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+
+// dbSource - the structure that describes how to open the database the DSN points to.
+type dbSource struct {
+	driver          string
+	dsn             string
+	migrationFolder string
+}
+
+// parseDSN - the function that selects the database driver by the DSN: "sqlite:///path/to/file.db"
+// opens SQLite with its own migrations subfolder, anything else is treated as Postgres.
+func parseDSN(cfg *config.Postgres) dbSource {
+	path, ok := strings.CutPrefix(cfg.DSN, sqliteScheme)
+	if !ok {
+		return dbSource{driver: "pgx", dsn: cfg.DSN, migrationFolder: cfg.MigrationFolder}
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	return dbSource{
+		driver:          "sqlite",
+		dsn:             path + separator + sqlitePragmas,
+		migrationFolder: filepath.Join(cfg.MigrationFolder, "sqlite"),
+	}
+}
+
 // Migrate - function to perform database migration operations.
 func Migrate(ctx context.Context, cfg *config.Postgres) {
-	db, err := goose.OpenDBWithDriver("pgx", cfg.DSN)
+	source := parseDSN(cfg)
+
+	db, err := goose.OpenDBWithDriver(source.driver, source.dsn)
 	if err != nil {
 		logger.Fatal(ctx, "failed to open DB for migration", err)
 	}
@@ -33,7 +72,7 @@ func Migrate(ctx context.Context, cfg *config.Postgres) {
 		ctx,
 		"up",
 		db,
-		cfg.MigrationFolder,
+		source.migrationFolder,
 	); err != nil {
 		logger.Fatal(ctx, "failed to run up DB migration", err)
 	}
@@ -45,7 +84,8 @@ func InitDB(ctx context.Context, cfg *config.Postgres) (*sqlx.DB, error) {
 	Migrate(ctx, cfg)
 
 	logger.Info(ctx, "initializing db")
-	db, err := sqlx.Open("pgx", cfg.DSN)
+	source := parseDSN(cfg)
+	db, err := sqlx.Open(source.driver, source.dsn)
 	if err != nil {
 		return nil, fmt.Errorf("make sql db instance error: %w", err)
 	}
@@ -56,6 +96,11 @@ func InitDB(ctx context.Context, cfg *config.Postgres) (*sqlx.DB, error) {
 	db.SetConnMaxLifetime(0)
 	db.SetConnMaxIdleTime(0)
 	db.SetMaxOpenConns(cfg.MaxOpenConn)
+	if source.driver == "sqlite" {
+		// SQLite allows a single writer, a single connection queues writes instead of
+		// failing them with SQLITE_BUSY.
+		db.SetMaxOpenConns(1)
+	}
 	db.SetMaxIdleConns(cfg.IdleConn)
 
 	go func() {
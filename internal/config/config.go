@@ -3,12 +3,20 @@
 package config
 
 import (
+	"context"
 	"fmt"
-	"net"
+	"os"
+	"path/filepath"
 	"time"
 
+	"metrix/internal/netpolicy"
+	"metrix/pkg/crypto"
+	"metrix/pkg/logger"
+
 	"github.com/caarlos0/env/v6"
+	"github.com/fsnotify/fsnotify"
 	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 )
 
 // AppMode - the string-based type that defines running mode for the service,
@@ -26,6 +34,9 @@ const (
 	LocalAppMode AppMode = "local"
 )
 
+// defaultAlertRulesFile - the alerting rules file looked up next to the config file.
+const defaultAlertRulesFile = "alerts.yaml"
+
 // Postgres - the structure for postgresql config.
 type Postgres struct {
 	DSN             string        `env:"DSN"              envDefault:""`
@@ -37,43 +48,153 @@ type Postgres struct {
 
 // Config - the structure for general config.
 type Config struct {
-	AppMode              AppMode  `env:"APP_MODE"          envDefault:"local"           flag:"mode"              flagShort:"m" flagDescription:"application mode"`
-	HTTPAddress          string   `env:"ADDRESS"           envDefault:"localhost:8080"  flag:"address"           flagShort:"a" flagDescription:"http address"`
-	StoreInterval        int64    `env:"STORE_INTERVAL"    envDefault:"300"             flag:"store_interval"    flagShort:"s" flagDescription:"interval for storage backup"`
-	FileStoragePath      string   `env:"FILE_STORAGE_PATH" envDefault:""                flag:"file_storage_path" flagShort:"f" flagDescription:"filepath storage backup"`
-	Restore              bool     `env:"RESTORE"           envDefault:"false"           flag:"restore"           flagShort:"r" flagDescription:"boolean to restore from backup"`
-	LogLevel             string   `env:"LOG_LEVEL"         envDefault:"info"            flag:"log_level"         flagShort:"l" flagDescription:"level for logging"`
-	LogFile              string   `env:"LOG_FILE"          envDefault:"logs/logs.jsonl" flag:"log_file"          flagShort:"w" flagDescription:"filepath for logs"`
-	Postgres             Postgres `envPrefix:"DATABASE_"                                flag:"pg_dsn"            flagShort:"d" flagDescription:"database dsn"`
-	SignKey              string   `env:"KEY"                                            flag:"sign_key"          flagShort:"k" flagDescription:"a key using for signing"`
-	CryptoKey            string   `env:"CRYPTO_KEY"                                     flag:"crypto-key"        flagShort:"i" flagDescription:"crypto key"`
-	ConfigFile           string   `env:"CONFIG"`
-	TrustedSubNet        string   `env:"TRUSTED_SUBNET"    envDefault:"192.168.1.0/24"  flag:"trusted-subnet"   flagShort:"t"  flagDescription:"trusted subnet variable"`
-	GRPCAddress          string   `env:"GRPC_ADDRESS"      envDefault:"localhost:9090"  flag:"grpc-address"     flagShort:"g"  flagDescription:"grpc address"`
-	TrustedSubNetDefined *net.IPNet
+	AppMode              AppMode       `env:"APP_MODE"          envDefault:"local"           flag:"mode"              flagShort:"m" flagDescription:"application mode"`
+	HTTPAddress          string        `env:"ADDRESS"           envDefault:"localhost:8080"  flag:"address"           flagShort:"a" flagDescription:"http address"`
+	StoreInterval        int64         `env:"STORE_INTERVAL"    envDefault:"300"             flag:"store_interval"    flagShort:"s" flagDescription:"interval for storage snapshot"`
+	FileStoragePath      string        `env:"FILE_STORAGE_PATH" envDefault:""                flag:"file_storage_path" flagShort:"f" flagDescription:"filepath storage backup"`
+	Restore              bool          `env:"RESTORE"           envDefault:"false"           flag:"restore"           flagShort:"r" flagDescription:"boolean to restore from backup"`
+	BoltPath             string        `env:"BOLT_PATH"         envDefault:""                flag:"bolt-path"                       flagDescription:"bbolt database file, enables the embedded storage"`
+	SnapshotsKept        int64         `env:"SNAPSHOTS_KEPT"    envDefault:"3"               flag:"snapshots-kept"                  flagDescription:"number of storage snapshots kept for restore"`
+	LogLevel             string        `env:"LOG_LEVEL"         envDefault:"info"            flag:"log_level"         flagShort:"l" flagDescription:"level for logging"`
+	LogFile              string        `env:"LOG_FILE"          envDefault:"logs/logs.jsonl" flag:"log_file"          flagShort:"w" flagDescription:"filepath for logs"`
+	Postgres             Postgres      `envPrefix:"DATABASE_"                                flag:"pg_dsn"            flagShort:"d" flagDescription:"database dsn"`
+	SignKey              string        `env:"KEY"                                            flag:"sign_key"          flagShort:"k" flagDescription:"a key using for signing"`
+	CryptoKey            string        `env:"CRYPTO_KEY"                                     flag:"crypto-key"        flagShort:"i" flagDescription:"crypto key"`
+	ConfigFile           string        `env:"CONFIG"`
+	TrustedSubNet        string        `env:"TRUSTED_SUBNET"    envDefault:"192.168.1.0/24"  flag:"trusted-subnet"   flagShort:"t"  flagDescription:"comma separated allowed ipv4/ipv6 networks or addresses"`
+	DeniedSubNet         string        `env:"DENIED_SUBNET"     envDefault:""                flag:"denied-subnet"                   flagDescription:"comma separated denied ipv4/ipv6 networks or addresses"`
+	GRPCAddress          string        `env:"GRPC_ADDRESS"      envDefault:"localhost:9090"  flag:"grpc-address"     flagShort:"g"  flagDescription:"grpc address"`
+	HistoryRetention     int64         `env:"HISTORY_RETENTION" envDefault:"3600"            flag:"history-retention"              flagDescription:"seconds to keep metrics history"`
+	AlertRulesFile       string        `env:"ALERT_RULES_FILE"            envDefault:""    flag:"alert-rules"            flagDescription:"yaml file with alerting rules"`
+	AlertEvalInterval    int64         `env:"ALERT_EVAL_INTERVAL"         envDefault:"15"  flag:"alert-eval-interval"    flagDescription:"seconds between alerting rules evaluations"`
+	WebhookURLs          []string      `env:"WEBHOOK_URLS"                envSeparator:","`
+	WebhookGroupInterval int64         `env:"WEBHOOK_GROUP_INTERVAL"      envDefault:"30"  flag:"webhook-group-interval" flagDescription:"seconds to group alert notifications"`
+	WebhookRetryCount    int64         `env:"WEBHOOK_RETRY_COUNT"         envDefault:"3"   flag:"webhook-retry-count"    flagDescription:"webhook delivery retries"`
+	WebhookRetryWait     time.Duration `env:"WEBHOOK_RETRY_WAIT_TIME"     envDefault:"1s"`
+	WebhookRetryMaxWait  time.Duration `env:"WEBHOOK_RETRY_MAX_WAIT_TIME" envDefault:"30s"`
+	GRPCTLSCert          string        `env:"GRPC_TLS_CERT"               envDefault:""    flag:"grpc-tls-cert"          flagDescription:"grpc server certificate, enables tls"`
+	GRPCTLSKey           string        `env:"GRPC_TLS_KEY"                envDefault:""    flag:"grpc-tls-key"           flagDescription:"grpc server private key"`
+	GRPCClientCA         string        `env:"GRPC_CLIENT_CA"              envDefault:""    flag:"grpc-client-ca"         flagDescription:"ca verifying agent certificates, enables mutual tls"`
+	HTTPTLSCert          string        `env:"HTTP_TLS_CERT"               envDefault:""    flag:"http-tls-cert"          flagDescription:"http server certificate, enables https"`
+	HTTPTLSKey           string        `env:"HTTP_TLS_KEY"                envDefault:""    flag:"http-tls-key"           flagDescription:"http server private key"`
+	HTTPTLSMinVersion    string        `env:"HTTP_TLS_MIN_VERSION"        envDefault:"1.2" flag:"http-tls-min-version"   flagDescription:"minimum tls version for https: 1.2 or 1.3"`
+	HTTPClientCA         string        `env:"HTTP_CLIENT_CA"              envDefault:""    flag:"http-client-ca"         flagDescription:"ca verifying client certificates for https"`
+	TrustedProxies       string        `env:"TRUSTED_PROXIES"             envDefault:""          flag:"trusted-proxies"       flagDescription:"comma separated proxy networks allowed to set the client address headers"`
+	TrustedProxyHeaders  string        `env:"TRUSTED_PROXY_HEADERS"       envDefault:"X-Real-IP" flag:"trusted-proxy-headers" flagDescription:"comma separated headers carrying the client address"`
+	TrustedNetwork       *netpolicy.Policy
+
+	file *viper.Viper
 }
 
 // NewConfig - the builder function for new configuration.
 func NewConfig() (*Config, error) {
 	cfg := &Config{}
 
+	if err := env.Parse(cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse server envs: %w", err)
+	}
+
 	if cfg.ConfigFile != "" {
 		if err := readFromFile(cfg.ConfigFile, cfg); err != nil {
 			return nil, errors.Wrap(err, "failed to read from file")
 		}
 	}
 
-	if err := env.Parse(cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse server envs: %w", err)
+	parseFlags(cfg)
+
+	if cfg.AlertRulesFile == "" && cfg.ConfigFile != "" {
+		rulesFile := filepath.Join(filepath.Dir(cfg.ConfigFile), defaultAlertRulesFile)
+		if _, err := os.Stat(rulesFile); err == nil {
+			cfg.AlertRulesFile = rulesFile
+		}
 	}
 
-	parseFlags(cfg)
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
 
-	if _, TrustedSubNetDefined, err := net.ParseCIDR(cfg.TrustedSubNet); err != nil {
-		return cfg, errors.Wrap(err, "failed to define subnet")
-	} else {
-		cfg.TrustedSubNetDefined = TrustedSubNetDefined
+	trustedNetwork, err := netpolicy.Parse(
+		cfg.TrustedSubNet,
+		cfg.DeniedSubNet,
+		cfg.TrustedProxies,
+		cfg.TrustedProxyHeaders,
+	)
+	if err != nil {
+		return cfg, errors.Wrap(err, "failed to define trusted network")
 	}
+	cfg.TrustedNetwork = trustedNetwork
 
 	return cfg, nil
 }
+
+// validate - the method that checks the consistency of the parsed settings.
+func (cfg *Config) validate() error {
+	if (cfg.GRPCTLSCert == "") != (cfg.GRPCTLSKey == "") {
+		return errors.New("grpc tls certificate and key must be set together")
+	}
+
+	if cfg.GRPCClientCA != "" && cfg.GRPCTLSCert == "" {
+		return errors.New("grpc client ca requires grpc tls certificate")
+	}
+
+	if (cfg.HTTPTLSCert == "") != (cfg.HTTPTLSKey == "") {
+		return errors.New("http tls certificate and key must be set together")
+	}
+
+	if cfg.HTTPClientCA != "" && cfg.HTTPTLSCert == "" {
+		return errors.New("http client ca requires http tls certificate")
+	}
+
+	if cfg.Postgres.DSN != "" && cfg.BoltPath != "" {
+		return errors.New("database dsn and bolt path must not be set together")
+	}
+
+	if cfg.SnapshotsKept < 1 {
+		return errors.New("at least one storage snapshot must be kept")
+	}
+
+	if _, err := crypto.ParseTLSVersion(cfg.HTTPTLSMinVersion); err != nil {
+		return errors.Wrap(err, "failed to define http tls min version")
+	}
+
+	return nil
+}
+
+// WatchFile - the method that watches the config file and applies changes of the trusted
+// and denied networks and proxy headers to the running policy, other settings require restart.
+func (cfg *Config) WatchFile(ctx context.Context) {
+	if cfg.file == nil || cfg.TrustedNetwork == nil {
+		return
+	}
+
+	cfg.file.OnConfigChange(func(fsnotify.Event) {
+		if err := cfg.reloadTrustedNetwork(ctx); err != nil {
+			logger.Error(ctx, "failed to reload trusted network policy", err)
+		}
+	})
+	cfg.file.WatchConfig()
+}
+
+// reloadTrustedNetwork - the method that re-applies the network lists from the config file
+// with the same precedence as on start, the current policy is kept when the new lists are invalid.
+func (cfg *Config) reloadTrustedNetwork(ctx context.Context) error {
+	next := &Config{}
+	if err := env.Parse(next); err != nil {
+		return fmt.Errorf("failed to parse server envs: %w", err)
+	}
+	applyFile(cfg.file, next)
+	keepFlags(cfg, next, "TrustedSubNet", "DeniedSubNet", "TrustedProxies", "TrustedProxyHeaders")
+
+	if err := cfg.TrustedNetwork.UpdateList(
+		next.TrustedSubNet,
+		next.DeniedSubNet,
+		next.TrustedProxies,
+		next.TrustedProxyHeaders,
+	); err != nil {
+		return errors.Wrap(err, "failed to update trusted network")
+	}
+
+	logger.Info(ctx, "trusted network policy reloaded", "policy", cfg.TrustedNetwork.String())
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"metrix/internal/netpolicy"
+
+	"github.com/caarlos0/env/v6"
+)
+
+func writeConfigFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %s", err)
+	}
+}
+
+func TestReadFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeConfigFile(t, path, `{
+		"address": "0.0.0.0:9000",
+		"grpc-address": "0.0.0.0:9090",
+		"store_interval": 5,
+		"restore": true,
+		"TrustedSubNet": "10.0.0.0/8,fd00::/8",
+		"WebhookRetryWait": "2s",
+		"WebhookURLs": ["http://hook"],
+		"pg_dsn": "postgres://file"
+	}`)
+	t.Setenv("GRPC_ADDRESS", "127.0.0.1:7070")
+
+	cfg := &Config{}
+	if err := env.Parse(cfg); err != nil {
+		t.Fatalf("env.Parse() error = %v", err)
+	}
+	if err := readFromFile(path, cfg); err != nil {
+		t.Fatalf("readFromFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{name: "Test 1", got: cfg.HTTPAddress, want: "0.0.0.0:9000"},
+		{name: "Test 2", got: cfg.GRPCAddress, want: "127.0.0.1:7070"},
+		{name: "Test 3", got: cfg.StoreInterval, want: int64(5)},
+		{name: "Test 4", got: cfg.Restore, want: true},
+		{name: "Test 5", got: cfg.TrustedSubNet, want: "10.0.0.0/8,fd00::/8"},
+		{name: "Test 6", got: cfg.WebhookRetryWait, want: 2 * time.Second},
+		{name: "Test 7", got: len(cfg.WebhookURLs), want: 1},
+		{name: "Test 8", got: cfg.Postgres.DSN, want: "postgres://file"},
+		{name: "Test 9", got: cfg.LogLevel, want: "info"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+
+	if err := readFromFile(filepath.Join(t.TempDir(), "missing.json"), cfg); err == nil {
+		t.Errorf("readFromFile() expected error for missing file")
+	}
+}
+
+func TestConfig_WatchFile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeConfigFile(t, path, `{"TrustedSubNet": "10.0.0.0/8", "DeniedSubNet": "10.0.0.1"}`)
+
+	cfg := &Config{}
+	if err := env.Parse(cfg); err != nil {
+		t.Fatalf("env.Parse() error = %v", err)
+	}
+	if err := readFromFile(path, cfg); err != nil {
+		t.Fatalf("readFromFile() error = %v", err)
+	}
+
+	policy, err := netpolicy.Parse(cfg.TrustedSubNet, cfg.DeniedSubNet, cfg.TrustedProxies, cfg.TrustedProxyHeaders)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	cfg.TrustedNetwork = policy
+	cfg.WatchFile(ctx)
+
+	check := func(addr string) error {
+		_, err := policy.Check(func(string) string { return "" }, addr)
+		return err
+	}
+	if err := check("10.0.0.1:5000"); !errors.Is(err, netpolicy.ErrDenied) {
+		t.Fatalf("Check() error = %v, want denied", err)
+	}
+
+	writeConfigFile(t, path, `{"TrustedSubNet": "10.0.0.0/8,fd00::/8", "DeniedSubNet": "10.0.0.2"}`)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !errors.Is(check("10.0.0.2:5000"), netpolicy.ErrDenied) {
+		if time.Now().After(deadline) {
+			t.Fatalf("policy was not reloaded: %s", policy)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := check("10.0.0.1:5000"); err != nil {
+		t.Errorf("Check() error = %v, want allowed after reload", err)
+	}
+	if err := check("[fd00::1]:5000"); err != nil {
+		t.Errorf("Check() error = %v, want allowed after reload", err)
+	}
+
+	writeConfigFile(t, path, `{"TrustedSubNet": "10.0.0.0/33"}`)
+	time.Sleep(100 * time.Millisecond)
+	if err := check("10.0.0.2:5000"); !errors.Is(err, netpolicy.ErrDenied) {
+		t.Errorf("Check() error = %v, invalid file must keep the policy", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		minVersion string
+		wantErr    bool
+	}{
+		{name: "Test 1", minVersion: "1.2"},
+		{name: "Test 2", minVersion: "1.3"},
+		{name: "Test 3", minVersion: "1.1", wantErr: true},
+		{name: "Test 4", minVersion: "1.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{SnapshotsKept: 1, HTTPTLSMinVersion: tt.minVersion}
+			if err := cfg.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Config.validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
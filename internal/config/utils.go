@@ -1,7 +1,9 @@
 package config
 
 import (
+	"os"
 	"reflect"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
@@ -128,21 +130,91 @@ func parseFlags(cfg *Config) {
 	}
 }
 
+// readFromFile - the function that applies values from the json config file on top of
+// defaults, values set by environment variables or flags keep precedence. A key matches
+// either the field name or its flag name case-insensitively.
 func readFromFile(filePath string, cfg *Config) error {
-	viper.AddConfigPath(filePath)
-	viper.SetConfigType("json")
+	v := viper.New()
+	v.SetConfigFile(filePath)
+	v.SetConfigType("json")
 
-	viper.AutomaticEnv()
-
-	err := viper.ReadInConfig()
-	if err != nil {
+	if err := v.ReadInConfig(); err != nil {
 		return errors.Wrap(err, "failed to read config")
 	}
 
-	err = viper.Unmarshal(&cfg)
-	if err != nil {
-		return errors.Wrap(err, "failed to unmarshal")
-	}
+	applyFile(v, cfg)
+	cfg.file = v
 
 	return nil
 }
+
+// applyFile - the function that copies the config file values into the config fields
+// not set by environment variables or flags.
+func applyFile(v *viper.Viper, cfg *Config) {
+	value := reflect.ValueOf(cfg).Elem()
+	t := value.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if envName := field.Tag.Get("env"); envName != "" {
+			if _, ok := os.LookupEnv(envName); ok {
+				continue
+			}
+		}
+
+		flagName := field.Tag.Get("flag")
+		if flagName != "" && isFlagPassed(flagName) {
+			continue
+		}
+
+		key := fileKey(v, field.Name, flagName)
+		if key == "" {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		switch {
+		case field.Type == reflect.TypeOf(cfg.Postgres):
+			if _, ok := os.LookupEnv("DATABASE_DSN"); !ok {
+				cfg.Postgres.DSN = v.GetString(key)
+			}
+		case field.Type == reflect.TypeOf(time.Duration(0)):
+			fieldValue.SetInt(int64(v.GetDuration(key)))
+		case field.Type.Kind() == reflect.String:
+			fieldValue.SetString(v.GetString(key))
+		case field.Type.Kind() == reflect.Int64:
+			fieldValue.SetInt(v.GetInt64(key))
+		case field.Type.Kind() == reflect.Bool:
+			fieldValue.SetBool(v.GetBool(key))
+		case field.Type == reflect.TypeOf([]string{}):
+			fieldValue.Set(reflect.ValueOf(v.GetStringSlice(key)))
+		}
+	}
+}
+
+// keepFlags - the function that copies the named fields set by flags from cfg to next.
+func keepFlags(cfg *Config, next *Config, names ...string) {
+	for _, name := range names {
+		field, ok := reflect.TypeOf(*cfg).FieldByName(name)
+		if !ok || !isFlagPassed(field.Tag.Get("flag")) {
+			continue
+		}
+
+		reflect.ValueOf(next).Elem().FieldByName(name).Set(reflect.ValueOf(cfg).Elem().FieldByName(name))
+	}
+}
+
+// fileKey - the function that returns the first of the names present in the config file.
+func fileKey(v *viper.Viper, names ...string) string {
+	for _, name := range names {
+		if name != "" && v.IsSet(name) {
+			return name
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"metrix/internal/model"
+	"metrix/internal/repository"
+	"metrix/pkg/logger"
+)
+
+// AgentControllerImpl - implementation structure for the AgentController that manages
+// access to the agent repository.
+type AgentControllerImpl struct {
+	repoGroup *repository.Group
+}
+
+// NewAgentController - the builder function for the AgentControllerImpl.
+func NewAgentController(repoGroup *repository.Group) *AgentControllerImpl {
+	return &AgentControllerImpl{repoGroup: repoGroup}
+}
+
+// GetAll - the controller method that incapsulates buisiness logic for getting known agents
+// with their liveness status.
+func (a *AgentControllerImpl) GetAll(ctx context.Context) (*[]model.Agent, error) {
+	agents, err := a.repoGroup.AgentRepo.ReadAll(ctx)
+	if err != nil {
+		logger.Debug(ctx, fmt.Sprintf("failed to retrieve agents: %s", err))
+		return nil, fmt.Errorf("failed to retrieve agents: %w", err)
+	}
+
+	if agents == nil {
+		return &[]model.Agent{}, nil
+	}
+
+	now := time.Now().UTC()
+	for i := range *agents {
+		(*agents)[i].Status = (*agents)[i].StatusAt(now)
+	}
+
+	return agents, nil
+}
@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"metrix/internal/identity"
+	"metrix/internal/model"
+	"metrix/internal/repository"
+)
+
+func TestAgentControllerImpl_GetAll(t *testing.T) {
+	ctx := context.Background()
+	repoGroup := repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1)
+	metrics := NewMetricController(repoGroup)
+
+	for _, agentID := range []string{"host-b", "host-a", "host-b"} {
+		_, err := metrics.SetMany(
+			identity.WithAgentID(ctx, agentID),
+			[]*model.Metric{
+				{
+					ID:    "PollCount",
+					MType: model.CounterType,
+					Delta: func() *int64 { i := int64(1); return &i }(),
+				},
+			},
+		)
+		if err != nil {
+			t.Errorf("MetricControllerImpl.SetMany() error = %v", err)
+			return
+		}
+	}
+
+	tests := []struct {
+		name      string
+		labels    model.Labels
+		wantDelta int64
+	}{
+		{
+			name:      "Test 1: Agent host-a series",
+			labels:    model.Labels{model.AgentLabel: "host-a"},
+			wantDelta: 1,
+		},
+		{
+			name:      "Test 2: Agent host-b series",
+			labels:    model.Labels{model.AgentLabel: "host-b"},
+			wantDelta: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := metrics.Get(ctx, "PollCount", tt.labels)
+			if err != nil {
+				t.Errorf("MetricControllerImpl.Get() error = %v", err)
+				return
+			}
+			if got == nil || got.Delta == nil || *got.Delta != tt.wantDelta {
+				t.Errorf("MetricControllerImpl.Get() = %+v, want delta %d", got, tt.wantDelta)
+			}
+		})
+	}
+
+	got, err := metrics.Get(ctx, "PollCount", nil)
+	if err != nil || got != nil {
+		t.Errorf("MetricControllerImpl.Get() of series reported by several agents = %+v, %v, want nil", got, err)
+	}
+
+	a := NewAgentController(repoGroup)
+	agents, err := a.GetAll(ctx)
+	if err != nil {
+		t.Errorf("AgentControllerImpl.GetAll() error = %v", err)
+		return
+	}
+
+	ids := []string{}
+	for _, agent := range *agents {
+		if agent.LastSeen.IsZero() {
+			t.Errorf("AgentControllerImpl.GetAll() agent %s has no last seen time", agent.ID)
+		}
+		ids = append(ids, agent.ID)
+	}
+	if want := []string{"host-a", "host-b"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("AgentControllerImpl.GetAll() = %v, want %v", ids, want)
+	}
+}
+
+func TestAgentControllerImpl_GetAllStatus(t *testing.T) {
+	ctx := context.Background()
+	repoGroup := repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1)
+	now := time.Now().UTC()
+
+	agents := []*model.Agent{
+		{ID: "healthy", LastSeen: now.Add(-5 * time.Second), ReportInterval: 10},
+		{ID: "late", LastSeen: now.Add(-30 * time.Second), ReportInterval: 10},
+		{ID: "dead", LastSeen: now.Add(-time.Minute), ReportInterval: 10},
+		{ID: "undeclared", LastSeen: now.Add(-15 * time.Second)},
+		{ID: "slow", LastSeen: now.Add(-time.Minute), ReportInterval: 60},
+	}
+	for _, agent := range agents {
+		if err := repoGroup.AgentRepo.Touch(ctx, agent); err != nil {
+			t.Errorf("AgentRepository.Touch() error = %v", err)
+			return
+		}
+	}
+
+	a := NewAgentController(repoGroup)
+	got, err := a.GetAll(ctx)
+	if err != nil {
+		t.Errorf("AgentControllerImpl.GetAll() error = %v", err)
+		return
+	}
+
+	statuses := map[string]model.AgentStatus{}
+	for _, agent := range *got {
+		statuses[agent.ID] = agent.Status
+	}
+
+	want := map[string]model.AgentStatus{
+		"healthy":    model.HealthyAgentStatus,
+		"late":       model.LateAgentStatus,
+		"dead":       model.DeadAgentStatus,
+		"undeclared": model.HealthyAgentStatus,
+		"slow":       model.HealthyAgentStatus,
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("AgentControllerImpl.GetAll() statuses = %v, want %v", statuses, want)
+	}
+}
+
+func TestMetricControllerImpl_GetAgentSeries(t *testing.T) {
+	ctx := context.Background()
+	metrics := NewMetricController(repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1))
+
+	value := 1.5
+	_, err := metrics.Set(identity.WithAgentID(ctx, "host-a"), &model.Metric{
+		ID:     "Alloc",
+		MType:  model.GaugeType,
+		Value:  &value,
+		Labels: model.Labels{"dc": "eu"},
+	})
+	if err != nil {
+		t.Errorf("MetricControllerImpl.Set() error = %v", err)
+		return
+	}
+
+	tests := []struct {
+		name   string
+		id     string
+		labels model.Labels
+		found  bool
+	}{
+		{name: "Test 1", id: "Alloc", labels: model.Labels{"dc": "eu"}, found: true},
+		{name: "Test 2", id: "Alloc", labels: model.Labels{"dc": "eu", model.AgentLabel: "host-a"}, found: true},
+		{name: "Test 3", id: "Alloc"},
+		{name: "Test 4", id: "Alloc", labels: model.Labels{"dc": "eu", model.AgentLabel: "host-b"}},
+		{name: "Test 5", id: "Missing", labels: model.Labels{"dc": "eu"}},
+		{name: "Test 6", id: "Alloc", labels: model.Labels{"dc": "us"}},
+		{name: "Test 7", id: "Allo", labels: model.Labels{"dc": "eu"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := metrics.Get(ctx, tt.id, tt.labels)
+			if err != nil {
+				t.Errorf("MetricControllerImpl.Get() error = %v", err)
+				return
+			}
+			if (got != nil) != tt.found {
+				t.Errorf("MetricControllerImpl.Get() = %+v, found %v", got, tt.found)
+			}
+		})
+	}
+}
+
+func TestMetricControllerImpl_SetForeignAgentLabel(t *testing.T) {
+	ctx := context.Background()
+	metrics := NewMetricController(repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1))
+
+	delta := int64(1)
+	_, err := metrics.SetMany(identity.WithAgentID(ctx, "host-b"), []*model.Metric{
+		{ID: "PollCount", MType: model.CounterType, Delta: &delta},
+	})
+	if err != nil {
+		t.Errorf("MetricControllerImpl.SetMany() error = %v", err)
+		return
+	}
+
+	forged := int64(100)
+	_, err = metrics.Set(identity.WithAgentID(ctx, "host-a"), &model.Metric{
+		ID:     "PollCount",
+		MType:  model.CounterType,
+		Delta:  &forged,
+		Labels: model.Labels{model.AgentLabel: "host-b"},
+	})
+	if err != nil {
+		t.Errorf("MetricControllerImpl.Set() error = %v", err)
+		return
+	}
+
+	tests := []struct {
+		name      string
+		agentID   string
+		wantDelta int64
+	}{
+		{name: "Test 1", agentID: "host-b", wantDelta: 1},
+		{name: "Test 2", agentID: "host-a", wantDelta: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := metrics.Get(ctx, "PollCount", model.Labels{model.AgentLabel: tt.agentID})
+			if err != nil {
+				t.Errorf("MetricControllerImpl.Get() error = %v", err)
+				return
+			}
+			if got == nil || got.Delta == nil || *got.Delta != tt.wantDelta {
+				t.Errorf("MetricControllerImpl.Get() = %+v, want delta %d", got, tt.wantDelta)
+			}
+		})
+	}
+}
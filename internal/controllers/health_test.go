@@ -11,7 +11,7 @@ import (
 
 func TestHealthControllerImpl_SetLiveness(t *testing.T) {
 	ctx := context.Background()
-	repoGroup := repository.NewGroup(ctx, nil, "", 0, false)
+	repoGroup := repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1)
 	controller := NewHealthController(repoGroup)
 
 	assert.Equal(t, false, controller.LivenessState())
@@ -32,7 +32,7 @@ func TestHealthControllerImpl_SetLiveness(t *testing.T) {
 
 func TestHealthControllerImpl_SetReadiness(t *testing.T) {
 	ctx := context.Background()
-	repoGroup := repository.NewGroup(ctx, nil, "", 0, false)
+	repoGroup := repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1)
 	controller := NewHealthController(repoGroup)
 
 	assert.Equal(t, false, controller.ReadinessState())
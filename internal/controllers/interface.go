@@ -10,8 +10,15 @@ import (
 type MetricsController interface {
 	Set(ctx context.Context, metricIn *model.Metric) (*model.Metric, error)
 	SetMany(ctx context.Context, metricsIn []*model.Metric) (bool, error)
-	Get(ctx context.Context, metricID string) (*model.Metric, error)
-	GetIDs(ctx context.Context) (*[]string, error)
+	Get(ctx context.Context, metricID string, labels model.Labels) (*model.Metric, error)
+	GetIDs(ctx context.Context, selector model.Labels) (*[]string, error)
+	GetAll(ctx context.Context, selector model.Labels) (*[]model.Metric, error)
+	GetRange(ctx context.Context, query *model.RangeQuery) (*model.RangeResult, error)
+}
+
+// AgentController - the interface that describes all the AgentController methods.
+type AgentController interface {
+	GetAll(ctx context.Context) (*[]model.Agent, error)
 }
 
 // HealthController - the interface that describes all the HealthController methods.
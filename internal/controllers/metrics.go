@@ -3,7 +3,9 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"metrix/internal/identity"
 	"metrix/internal/model"
 	"metrix/internal/repository"
 	"metrix/pkg/logger"
@@ -26,27 +28,36 @@ func (m *MetricControllerImpl) Set(
 	ctx context.Context,
 	metricIn *model.Metric,
 ) (*model.Metric, error) {
-	metric, err := m.repoGroup.MetricRepo.Read(ctx, metricIn.ID)
+	agentID, _ := identity.AgentIDFromContext(ctx)
+	metricIn.WithAgent(agentID)
+
+	metric, err := m.repoGroup.MetricRepo.Read(ctx, metricIn.SeriesKey())
 	if err != nil {
 		logger.Debug(ctx, fmt.Sprintf("failed to retrieve structure: %s", err))
 		return nil, fmt.Errorf("failed to retrieve metric: %w", err)
 	}
 
 	if metric != nil {
-		metric.SetValue(metricIn.Delta, metricIn.Value)
+		metric.SetValue(metricIn)
 		metric, err = m.repoGroup.MetricRepo.Update(ctx, metric)
 		if err != nil {
 			logger.Debug(ctx, fmt.Sprintf("failed to update metric: %s", err))
 			return nil, fmt.Errorf("failed to update metric: %w", err)
 		}
 	} else {
-		metric, err = m.repoGroup.MetricRepo.Create(ctx, metricIn)
+		metric = &model.Metric{ID: metricIn.ID, MType: metricIn.MType, Labels: metricIn.Labels}
+		metric.SetValue(metricIn)
+		metric, err = m.repoGroup.MetricRepo.Create(ctx, metric)
 		if err != nil {
 			logger.Debug(ctx, fmt.Sprintf("failed to insert metric: %s", err))
 			return nil, fmt.Errorf("failed to insert metric: %w", err)
 		}
 	}
 
+	if err := m.touchAgent(ctx, agentID); err != nil {
+		return nil, err
+	}
+
 	return metric, nil
 }
 
@@ -56,42 +67,44 @@ func (m *MetricControllerImpl) SetMany(
 	ctx context.Context,
 	metricsIn []*model.Metric,
 ) (bool, error) {
-	collapsedMapping := map[string]model.Metric{}
-	for _, metric := range metricsIn {
-		collapsed, ok := collapsedMapping[metric.ID]
-		if ok {
-			collapsed.SetValue(metric.Delta, metric.Value)
-			collapsedMapping[metric.ID] = collapsed
-		} else {
-			collapsedMapping[metric.ID] = *metric
-		}
-	}
+	agentID, _ := identity.AgentIDFromContext(ctx)
 
-	metricIDs := []string{}
-	for _, m := range collapsedMapping {
-		metricIDs = append(metricIDs, m.ID)
+	metricKeys := []string{}
+	for _, metric := range metricsIn {
+		metric.WithAgent(agentID)
+		metricKeys = append(metricKeys, metric.SeriesKey())
 	}
 
-	curMetrics, err := m.repoGroup.MetricRepo.ReadMany(ctx, metricIDs)
+	curMetrics, err := m.repoGroup.MetricRepo.ReadMany(ctx, metricKeys)
 	if err != nil {
 		return false, fmt.Errorf("failed to get current metrics: %w", err)
 	}
 	mapCurMetrics := map[string]model.Metric{}
 	if curMetrics != nil {
 		for _, metric := range *curMetrics {
-			mapCurMetrics[metric.ID] = metric
+			mapCurMetrics[metric.SeriesKey()] = metric
 		}
 	}
 
-	newMetricsIn := []model.Metric{}
-	for _, metric := range collapsedMapping {
-		curMetric, ok := mapCurMetrics[metric.ID]
-		if ok {
-			curMetric.SetValue(metric.Delta, metric.Value)
-			newMetricsIn = append(newMetricsIn, curMetric)
-		} else {
-			newMetricsIn = append(newMetricsIn, metric)
+	// Incoming metrics are applied one by one on top of the stored ones, so histogram
+	// observations and counter deltas are accumulated in the order of arrival.
+	updatedMetrics := map[string]model.Metric{}
+	for _, metric := range metricsIn {
+		key := metric.SeriesKey()
+		curMetric, ok := updatedMetrics[key]
+		if !ok {
+			curMetric, ok = mapCurMetrics[key]
 		}
+		if !ok {
+			curMetric = model.Metric{ID: metric.ID, MType: metric.MType, Labels: metric.Labels}
+		}
+		curMetric.SetValue(metric)
+		updatedMetrics[key] = curMetric
+	}
+
+	newMetricsIn := []model.Metric{}
+	for _, metric := range updatedMetrics {
+		newMetricsIn = append(newMetricsIn, metric)
 	}
 
 	status, err := m.repoGroup.MetricRepo.UpsertMany(ctx, newMetricsIn)
@@ -100,15 +113,87 @@ func (m *MetricControllerImpl) SetMany(
 		return false, fmt.Errorf("failed to upsert metrics: %w", err)
 	}
 
+	if err := m.touchAgent(ctx, agentID); err != nil {
+		return false, err
+	}
+
 	return status, nil
 }
 
-// Get - the controller method that incapsulates buisiness logic for getting metrics.
+// touchAgent - the method that refreshes the last seen time and the declared report interval
+// of the reporting agent.
+func (m *MetricControllerImpl) touchAgent(ctx context.Context, agentID string) error {
+	if agentID == "" || m.repoGroup.AgentRepo == nil {
+		return nil
+	}
+
+	agent := &model.Agent{ID: agentID, LastSeen: time.Now().UTC()}
+	if interval, ok := identity.ReportIntervalFromContext(ctx); ok {
+		agent.ReportInterval = int64(interval / time.Second)
+	}
+
+	if err := m.repoGroup.AgentRepo.Touch(ctx, agent); err != nil {
+		logger.Debug(ctx, fmt.Sprintf("failed to touch agent: %s", err))
+		return fmt.Errorf("failed to touch agent: %w", err)
+	}
+
+	return nil
+}
+
+// Get - the controller method that incapsulates buisiness logic for getting metrics,
+// the series is identified by metric ID and the exact set of labels. A read without
+// the agent label falls back to the series of a single reporting agent.
 func (m *MetricControllerImpl) Get(
 	ctx context.Context,
 	metricID string,
+	labels model.Labels,
 ) (*model.Metric, error) {
-	metric, err := m.repoGroup.MetricRepo.Read(ctx, metricID)
+	metric, err := m.repoGroup.MetricRepo.Read(ctx, model.SeriesKey(metricID, labels))
+	if err != nil {
+		logger.Debug(ctx, fmt.Sprintf("failed to retrieve structure: %s", err))
+		return nil, fmt.Errorf("failed to retrieve metric: %w", err)
+	}
+
+	if _, ok := labels[model.AgentLabel]; metric != nil || ok {
+		return metric, nil
+	}
+
+	return m.getAgentSeries(ctx, metricID, labels)
+}
+
+// getAgentSeries - the method that finds the series differing from the requested one only
+// by the agent label, nothing is returned when several agents report it. Candidates are
+// matched by series keys, so only the found series is read.
+func (m *MetricControllerImpl) getAgentSeries(
+	ctx context.Context,
+	metricID string,
+	labels model.Labels,
+) (*model.Metric, error) {
+	ids, err := m.repoGroup.MetricRepo.ReadIDs(ctx)
+	if err != nil {
+		logger.Debug(ctx, fmt.Sprintf("failed to retrieve ids: %s", err))
+		return nil, fmt.Errorf("failed to retrieve ids: %w", err)
+	}
+	if ids == nil {
+		return nil, nil
+	}
+
+	found := ""
+	for _, key := range *ids {
+		if !model.IsAgentSeries(key, metricID, labels) {
+			continue
+		}
+		if found != "" {
+			return nil, nil
+		}
+		found = key
+	}
+
+	if found == "" {
+		return nil, nil
+	}
+
+	metric, err := m.repoGroup.MetricRepo.Read(ctx, found)
 	if err != nil {
 		logger.Debug(ctx, fmt.Sprintf("failed to retrieve structure: %s", err))
 		return nil, fmt.Errorf("failed to retrieve metric: %w", err)
@@ -117,13 +202,144 @@ func (m *MetricControllerImpl) Get(
 	return metric, nil
 }
 
-// GetIDs - the controller method that incapsulates buisiness logic for getting metrics ids.
-func (m *MetricControllerImpl) GetIDs(ctx context.Context) (*[]string, error) {
+// GetIDs - the controller method that incapsulates buisiness logic for getting metrics series
+// keys, only series having all the selector labels are returned.
+func (m *MetricControllerImpl) GetIDs(ctx context.Context, selector model.Labels) (*[]string, error) {
+	if len(selector) == 0 {
+		ids, err := m.repoGroup.MetricRepo.ReadIDs(ctx)
+		if err != nil {
+			logger.Debug(ctx, fmt.Sprintf("failed to retrieve ids: %s", err))
+			return nil, fmt.Errorf("failed to retrieve ids: %w", err)
+		}
+
+		return ids, nil
+	}
+
+	metrics, err := m.GetAll(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for i := range *metrics {
+		ids = append(ids, (*metrics)[i].SeriesKey())
+	}
+
+	return &ids, nil
+}
+
+// GetAll - the controller method that incapsulates buisiness logic for getting all stored metrics,
+// only series having all the selector labels are returned.
+func (m *MetricControllerImpl) GetAll(ctx context.Context, selector model.Labels) (*[]model.Metric, error) {
 	ids, err := m.repoGroup.MetricRepo.ReadIDs(ctx)
 	if err != nil {
 		logger.Debug(ctx, fmt.Sprintf("failed to retrieve ids: %s", err))
 		return nil, fmt.Errorf("failed to retrieve ids: %w", err)
 	}
 
-	return ids, nil
+	if ids == nil || len(*ids) == 0 {
+		return &[]model.Metric{}, nil
+	}
+
+	metrics, err := m.repoGroup.MetricRepo.ReadMany(ctx, *ids)
+	if err != nil {
+		logger.Debug(ctx, fmt.Sprintf("failed to retrieve metrics: %s", err))
+		return nil, fmt.Errorf("failed to retrieve metrics: %w", err)
+	}
+
+	if len(selector) == 0 || metrics == nil {
+		return metrics, nil
+	}
+
+	matched := []model.Metric{}
+	for _, metric := range *metrics {
+		if metric.Labels.Matches(selector) {
+			matched = append(matched, metric)
+		}
+	}
+
+	return &matched, nil
+}
+
+// GetRange - the controller method that incapsulates buisiness logic for getting metric
+// history downsampled to the query step.
+func (m *MetricControllerImpl) GetRange(
+	ctx context.Context,
+	query *model.RangeQuery,
+) (*model.RangeResult, error) {
+	key := model.SeriesKey(query.ID, query.Labels)
+	samples, err := m.repoGroup.MetricRepo.ReadRange(ctx, key, query.From, query.To)
+	if err != nil {
+		logger.Debug(ctx, fmt.Sprintf("failed to retrieve history: %s", err))
+		return nil, fmt.Errorf("failed to retrieve history: %w", err)
+	}
+
+	result := &model.RangeResult{
+		ID:          query.ID,
+		Labels:      query.Labels,
+		Aggregation: query.Aggregation,
+		Step:        query.Step.Seconds(),
+		Points:      []model.Point{},
+	}
+	if samples != nil {
+		result.Points = downsample(*samples, query)
+	}
+
+	return result, nil
+}
+
+// downsample - the function that aggregates time ordered samples into points,
+// one per step, aligned to the query start. Steps without samples are skipped.
+func downsample(samples []model.Sample, query *model.RangeQuery) []model.Point {
+	points := []model.Point{}
+
+	var (
+		bucket int64 = -1
+		count  int
+		acc    float64
+	)
+
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		if query.Aggregation == model.AvgAggregation {
+			acc /= float64(count)
+		}
+		points = append(points, model.Point{
+			Timestamp: query.From.Add(time.Duration(bucket) * query.Step),
+			Value:     acc,
+		})
+	}
+
+	for i := range samples {
+		ts := samples[i].Timestamp
+		if ts.Before(query.From) || ts.After(query.To) {
+			continue
+		}
+
+		value := samples[i].Float()
+		idx := int64(ts.Sub(query.From) / query.Step)
+		if idx != bucket {
+			flush()
+			bucket, count, acc = idx, 0, value
+		}
+
+		switch query.Aggregation {
+		case model.AvgAggregation:
+			if count > 0 {
+				acc += value
+			}
+		case model.MinAggregation:
+			acc = min(acc, value)
+		case model.MaxAggregation:
+			acc = max(acc, value)
+		case model.LastAggregation:
+			acc = value
+		}
+		count++
+	}
+	flush()
+
+	return points
 }
@@ -6,11 +6,12 @@ import (
 	"metrix/internal/repository"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestMetricControllerImpl_Set(t *testing.T) {
 	ctx := context.Background()
-	repoGroup := repository.NewGroup(ctx, nil, "", 0, false)
+	repoGroup := repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1)
 
 	type fields struct {
 		repoGroup *repository.Group
@@ -93,6 +94,82 @@ func TestMetricControllerImpl_Set(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:   "Test 5: Create Histogram Metric record",
+			fields: fields{repoGroup: repoGroup},
+			args: args{
+				metric: &model.Metric{
+					ID:    "Metric 3",
+					MType: "histogram",
+					Histogram: &model.Histogram{
+						Bounds: []float64{0.1, 1},
+						Counts: []int64{1, 2, 0},
+						Sum:    1.2,
+						Count:  3,
+					},
+				},
+			},
+			want: &model.Metric{
+				ID:    "Metric 3",
+				MType: "histogram",
+				Histogram: &model.Histogram{
+					Bounds: []float64{0.1, 1},
+					Counts: []int64{1, 2, 0},
+					Sum:    1.2,
+					Count:  3,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:   "Test 6: Merge Histogram Metric record",
+			fields: fields{repoGroup: repoGroup},
+			args: args{
+				metric: &model.Metric{
+					ID:    "Metric 3",
+					MType: "histogram",
+					Histogram: &model.Histogram{
+						Bounds: []float64{0.1, 1},
+						Counts: []int64{0, 1, 1},
+						Sum:    2.5,
+						Count:  2,
+					},
+				},
+			},
+			want: &model.Metric{
+				ID:    "Metric 3",
+				MType: "histogram",
+				Histogram: &model.Histogram{
+					Bounds: []float64{0.1, 1},
+					Counts: []int64{1, 3, 1},
+					Sum:    3.7,
+					Count:  5,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:   "Test 7: Observe value into Histogram Metric record",
+			fields: fields{repoGroup: repoGroup},
+			args: args{
+				metric: &model.Metric{
+					ID:    "Metric 3",
+					MType: "histogram",
+					Value: func() *float64 { i := float64(0.05); return &i }(),
+				},
+			},
+			want: &model.Metric{
+				ID:    "Metric 3",
+				MType: "histogram",
+				Histogram: &model.Histogram{
+					Bounds: []float64{0.1, 1},
+					Counts: []int64{2, 3, 1},
+					Sum:    3.75,
+					Count:  6,
+				},
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -114,7 +191,7 @@ func TestMetricControllerImpl_Set(t *testing.T) {
 
 func TestMetricControllerImpl_Get(t *testing.T) {
 	ctx := context.Background()
-	repoGroup := repository.NewGroup(ctx, nil, "", 0, false)
+	repoGroup := repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1)
 
 	_, err := repoGroup.MetricRepo.Create(
 		ctx,
@@ -142,11 +219,26 @@ func TestMetricControllerImpl_Get(t *testing.T) {
 		return
 	}
 
+	_, err = repoGroup.MetricRepo.Create(
+		ctx,
+		&model.Metric{
+			ID:     "Metric 1",
+			MType:  "counter",
+			Labels: model.Labels{"host": "h42"},
+			Delta:  func() *int64 { i := int64(50); return &i }(),
+		},
+	)
+	if err != nil {
+		t.Errorf("MetricControllerImpl.Get() error = %v", err)
+		return
+	}
+
 	type fields struct {
 		repoGroup *repository.Group
 	}
 	type args struct {
 		metricID string
+		labels   model.Labels
 	}
 	tests := []struct {
 		name    string
@@ -181,6 +273,31 @@ func TestMetricControllerImpl_Get(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:   "Test 3: Get Labeled Counter Metric record",
+			fields: fields{repoGroup: repoGroup},
+			args: args{
+				metricID: "Metric 1",
+				labels:   model.Labels{"host": "h42"},
+			},
+			want: &model.Metric{
+				ID:     "Metric 1",
+				MType:  "counter",
+				Labels: model.Labels{"host": "h42"},
+				Delta:  func() *int64 { i := int64(50); return &i }(),
+			},
+			wantErr: false,
+		},
+		{
+			name:   "Test 4: Get Unknown Labeled Metric record",
+			fields: fields{repoGroup: repoGroup},
+			args: args{
+				metricID: "Metric 1",
+				labels:   model.Labels{"host": "h43"},
+			},
+			want:    nil,
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -188,7 +305,7 @@ func TestMetricControllerImpl_Get(t *testing.T) {
 			m := &MetricControllerImpl{
 				repoGroup: tt.fields.repoGroup,
 			}
-			got, err := m.Get(ctx, tt.args.metricID)
+			got, err := m.Get(ctx, tt.args.metricID, tt.args.labels)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("MetricControllerImpl.Get() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -202,7 +319,7 @@ func TestMetricControllerImpl_Get(t *testing.T) {
 
 func TestMetricControllerImpl_SetMany(t *testing.T) {
 	ctx := context.Background()
-	repoGroup := repository.NewGroup(ctx, nil, "", 0, false)
+	repoGroup := repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1)
 
 	type fields struct {
 		repoGroup *repository.Group
@@ -254,3 +371,105 @@ func TestMetricControllerImpl_SetMany(t *testing.T) {
 		})
 	}
 }
+
+func Test_downsample(t *testing.T) {
+	from := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	sample := func(offset time.Duration, value float64) model.Sample {
+		return model.Sample{
+			ID:        "Metric 1",
+			MType:     model.GaugeType,
+			Timestamp: from.Add(offset),
+			Value:     &value,
+		}
+	}
+	samples := []model.Sample{
+		sample(0, 1),
+		sample(10*time.Second, 3),
+		sample(20*time.Second, 2),
+		sample(70*time.Second, 10),
+		sample(200*time.Second, 5),
+	}
+
+	type args struct {
+		samples []model.Sample
+		query   *model.RangeQuery
+	}
+	tests := []struct {
+		name string
+		args args
+		want []model.Point
+	}{
+		{
+			name: "Test 1: Average per minute",
+			args: args{
+				samples: samples,
+				query: &model.RangeQuery{
+					From:        from,
+					To:          from.Add(3 * time.Minute),
+					Step:        time.Minute,
+					Aggregation: model.AvgAggregation,
+				},
+			},
+			want: []model.Point{
+				{Timestamp: from, Value: 2},
+				{Timestamp: from.Add(time.Minute), Value: 10},
+			},
+		},
+		{
+			name: "Test 2: Min per minute",
+			args: args{
+				samples: samples,
+				query: &model.RangeQuery{
+					From:        from,
+					To:          from.Add(4 * time.Minute),
+					Step:        time.Minute,
+					Aggregation: model.MinAggregation,
+				},
+			},
+			want: []model.Point{
+				{Timestamp: from, Value: 1},
+				{Timestamp: from.Add(time.Minute), Value: 10},
+				{Timestamp: from.Add(3 * time.Minute), Value: 5},
+			},
+		},
+		{
+			name: "Test 3: Max per two minutes",
+			args: args{
+				samples: samples,
+				query: &model.RangeQuery{
+					From:        from,
+					To:          from.Add(4 * time.Minute),
+					Step:        2 * time.Minute,
+					Aggregation: model.MaxAggregation,
+				},
+			},
+			want: []model.Point{
+				{Timestamp: from, Value: 10},
+				{Timestamp: from.Add(2 * time.Minute), Value: 5},
+			},
+		},
+		{
+			name: "Test 4: Last per minute",
+			args: args{
+				samples: samples,
+				query: &model.RangeQuery{
+					From:        from,
+					To:          from.Add(time.Minute),
+					Step:        time.Minute,
+					Aggregation: model.LastAggregation,
+				},
+			},
+			want: []model.Point{
+				{Timestamp: from, Value: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := downsample(tt.args.samples, tt.args.query)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("downsample() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
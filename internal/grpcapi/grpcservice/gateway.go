@@ -0,0 +1,23 @@
+package grpcservice
+
+import (
+	"context"
+	"net/http"
+
+	pbv2 "metrix/internal/grpcapi/proto/v2"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/pkg/errors"
+)
+
+// Gateway - the method that builds REST handler generated from the v2 proto annotations,
+// requests are served in-process by the same implementation as the gRPC API.
+// Streaming methods have no REST mapping.
+func (gs *GServiceServer) Gateway(ctx context.Context) (http.Handler, error) {
+	mux := runtime.NewServeMux()
+	if err := pbv2.RegisterMetricServiceHandlerServer(ctx, mux, gs.V2()); err != nil {
+		return nil, errors.Wrap(err, "failed to register gateway handlers")
+	}
+
+	return mux, nil
+}
@@ -2,73 +2,177 @@ package grpcservice
 
 import (
 	"context"
-	"fmt"
+	"crypto/tls"
 	"metrix/internal/closer"
+	"metrix/internal/controllers"
 	pb "metrix/internal/grpcapi/proto/v1"
+	pbv2 "metrix/internal/grpcapi/proto/v2"
+	"metrix/internal/identity"
 	"metrix/internal/model"
+	"metrix/internal/netpolicy"
 	"metrix/internal/repository"
 	"metrix/pkg/logger"
 	"net"
-	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 )
 
+// Subscriber - the interface of a source of stored metric updates.
+type Subscriber interface {
+	Subscribe(buffer int) (<-chan model.Metric, func())
+}
+
+// GServiceServer - the structure that implements gRPC metric service, writes are applied
+// by the same metrics controller that backs the HTTP API.
 type GServiceServer struct {
 	pb.UnimplementedMetricServiceServer
 	Repository repository.MetricRepository
+	Controller controllers.MetricsController
+	Updates    Subscriber
+	Health     controllers.HealthController
 }
 
-func NewGServiceServer(metricsRepo repository.MetricRepository) *GServiceServer {
+func NewGServiceServer(
+	metricsRepo repository.MetricRepository,
+	agentRepo repository.AgentRepository,
+	updates Subscriber,
+	health controllers.HealthController,
+) *GServiceServer {
+	repoGroup := &repository.Group{
+		MetricRepo: metricsRepo,
+		AgentRepo:  agentRepo,
+	}
+
 	return &GServiceServer{
 		Repository: metricsRepo,
+		Controller: controllers.NewMetricController(repoGroup),
+		Updates:    updates,
+		Health:     health,
 	}
 }
 
-func subnetInterceptor(
-	subnet *net.IPNet,
-) func(context.Context, interface{}, *grpc.UnaryServerInfo, grpc.UnaryHandler) (interface{}, error) {
-	return func(
-		ctx context.Context,
-		req interface{},
-		info *grpc.UnaryServerInfo,
-		handler grpc.UnaryHandler,
-	) (interface{}, error) {
-		p, ok := peer.FromContext(ctx)
-		if !ok {
-			return nil, status.Error(codes.PermissionDenied, "Failed to get peer from context")
+// agentFromMetadata - the function that retrieves the reporting agent from incoming metadata,
+// nil is returned for anonymous requests. With mutual tls the agent is identified by the common
+// name of its verified certificate and the metadata may only repeat it.
+func agentFromMetadata(ctx context.Context) (*model.Agent, error) {
+	agentID := ""
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		if values := md.Get(identity.AgentIDMetadataKey); len(values) > 0 {
+			agentID = values[0]
 		}
+	}
 
-		ipPort := strings.Split(p.Addr.String(), ":")
-		ipStr := ipPort[0]
-		clientIP := net.ParseIP(ipStr)
-		logger.Debug(ctx, fmt.Sprintf("request from ip %s", clientIP))
+	if certID := peerCertificateID(ctx); certID != "" {
+		if agentID != "" && agentID != certID {
+			return nil, status.Errorf(
+				codes.PermissionDenied,
+				"agent id %q does not match client certificate %q", agentID, certID,
+			)
+		}
+		agentID = certID
+	}
 
-		if subnet != nil && (clientIP == nil || !subnet.Contains(clientIP)) {
-			logger.Warn(ctx, fmt.Sprintf("request from ip %s blocked", clientIP))
-			return nil, status.Error(codes.PermissionDenied, "Access denied")
+	if agentID == "" {
+		return nil, nil
+	}
+
+	if err := identity.ValidateAgentID(agentID); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	agent := &model.Agent{ID: agentID, LastSeen: time.Now().UTC()}
+	if values := md.Get(identity.ReportIntervalMetadataKey); len(values) > 0 {
+		interval, err := identity.ParseReportInterval(values[0])
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
 		}
+		agent.ReportInterval = int64(interval / time.Second)
+	}
+
+	return agent, nil
+}
 
-		return handler(ctx, req)
+// peerCertificateID - the function that returns the common name of the verified
+// client certificate, empty string is returned when the client was not authenticated.
+func peerCertificateID(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.AuthInfo == nil {
+		return ""
 	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	return info.State.VerifiedChains[0][0].Subject.CommonName
+}
+
+// NewServer - the method that builds gRPC server with both API versions, health checking
+// and reflection registered.
+// Requests pass the same policy as the HTTP API: trusted network check, logging, signature
+// verification and compression. The connection is secured when tlsConfig is set.
+func (gs *GServiceServer) NewServer(
+	policy *netpolicy.Policy,
+	tlsConfig *tls.Config,
+	signKey string,
+) *grpc.Server {
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			subnetInterceptor(policy),
+			loggingInterceptor,
+			signatureInterceptor(signKey),
+			compressionInterceptor,
+		),
+		grpc.ChainStreamInterceptor(
+			subnetStreamInterceptor(policy),
+			loggingStreamInterceptor,
+			signatureStreamInterceptor(signKey),
+			compressionStreamInterceptor,
+		),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	gServer := grpc.NewServer(opts...)
+	pb.RegisterMetricServiceServer(gServer, gs)
+	pbv2.RegisterMetricServiceServer(gServer, gs.V2())
+	if gs.Health != nil {
+		healthpb.RegisterHealthServer(gServer, NewHealthServer(gs.Health))
+	}
+	reflection.Register(gServer)
+
+	return gServer
 }
 
-func (gs *GServiceServer) Start(ctx context.Context, address string, trustedSubnet *net.IPNet) {
-	gServer := grpc.NewServer(grpc.UnaryInterceptor(subnetInterceptor(trustedSubnet)))
+func (gs *GServiceServer) Start(
+	ctx context.Context,
+	address string,
+	policy *netpolicy.Policy,
+	tlsConfig *tls.Config,
+	signKey string,
+) {
+	gServer := gs.NewServer(policy, tlsConfig, signKey)
 
 	go func() {
-		logger.Info(ctx, "starting listening http srv at "+address)
+		logger.Info(ctx, "starting listening grpc srv at "+address)
 
 		tcpListen, err := net.Listen("tcp", address)
 		if err != nil {
 			logger.Fatal(ctx, "failed to start listen tcp", err)
 		}
 
-		pb.RegisterMetricServiceServer(gServer, gs)
 		if err := gServer.Serve(tcpListen); err != nil {
 			logger.Fatal(ctx, "failed to serve grpc server", err)
 		}
@@ -86,35 +190,107 @@ func (gs *GServiceServer) SetMetrics(
 	ctx context.Context,
 	in *pb.MetricsRequest,
 ) (*pb.MetricsResponse, error) {
+	agent, err := agentFromMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := fromProto(in.GetItems())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := gs.store(ctx, agent, metrics); err != nil {
+		return nil, err
+	}
+
+	return &pb.MetricsResponse{
+		Status:  true,
+		Message: "metrics accepted",
+	}, nil
+}
+
+// store - the method that persists metrics with the metrics controller on behalf of the agent,
+// so counters and histograms are accumulated exactly as with the HTTP API. Without agent
+// metadata the identity already carried by the context is used.
+func (gs *GServiceServer) store(
+	ctx context.Context,
+	agent *model.Agent,
+	metrics []model.Metric,
+) error {
+	if agent != nil {
+		ctx = identity.WithAgentID(ctx, agent.ID)
+		if agent.ReportInterval > 0 {
+			ctx = identity.WithReportInterval(ctx, time.Duration(agent.ReportInterval)*time.Second)
+		}
+	}
+
+	metricsIn := make([]*model.Metric, 0, len(metrics))
+	for i := range metrics {
+		metricsIn = append(metricsIn, &metrics[i])
+	}
+
+	if _, err := gs.Controller.SetMany(ctx, metricsIn); err != nil {
+		return errors.Wrap(err, "failed to set many")
+	}
+
+	return nil
+}
+
+// fromProto - the function that converts protobuf metrics to the model.
+func fromProto(items []*pb.Metric) ([]model.Metric, error) {
 	metrics := []model.Metric{}
 
-	for _, m := range in.GetItems() {
+	for _, m := range items {
+		var labels model.Labels
+		if len(m.GetLabels()) > 0 {
+			labels = model.Labels(m.GetLabels())
+			if err := labels.Validate(); err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+		}
+
 		switch m.GetMtype() {
 		case pb.Metric_COUNTER:
 			delta := int64(m.GetValue())
 			metrics = append(metrics, model.Metric{
-				ID:    m.GetId(),
-				MType: model.CounterType,
-				Delta: &delta,
+				ID:     m.GetId(),
+				MType:  model.CounterType,
+				Labels: labels,
+				Delta:  &delta,
 			})
 		case pb.Metric_GAUGE:
 			value := float64(m.GetValue())
 			metrics = append(metrics, model.Metric{
-				ID:    m.GetId(),
-				MType: model.CounterType,
-				Value: &value,
+				ID:     m.GetId(),
+				MType:  model.GaugeType,
+				Labels: labels,
+				Value:  &value,
+			})
+		case pb.Metric_HISTOGRAM:
+			h := m.GetHistogram()
+			if h == nil {
+				return nil, errors.New("histogram metric without histogram payload")
+			}
+			histogram := &model.Histogram{
+				Bounds: h.GetBounds(),
+				Counts: h.GetCounts(),
+				Sum:    h.GetSum(),
+				Count:  h.GetCount(),
+			}
+			if err := histogram.Validate(); err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+			metrics = append(metrics, model.Metric{
+				ID:        m.GetId(),
+				MType:     model.HistogramType,
+				Labels:    labels,
+				Histogram: histogram,
 			})
 		default:
 			return nil, errors.New("unsupported metric type")
 		}
 	}
 
-	if _, err := gs.Repository.UpsertMany(ctx, metrics); err != nil {
-		return nil, errors.Wrap(err, "failed to upsert many")
-	}
-
-	return &pb.MetricsResponse{
-		Status:  true,
-		Message: "metrics accepted",
-	}, nil
+	return metrics, nil
 }
@@ -0,0 +1,45 @@
+package grpcservice
+
+import (
+	"testing"
+
+	pb "metrix/internal/grpcapi/proto/v1"
+	"metrix/internal/model"
+)
+
+func TestFromProto(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        *pb.Metric
+		wantType  model.MType
+		wantDelta bool
+		wantValue bool
+	}{
+		{
+			name:      "Test 1",
+			in:        &pb.Metric{Id: "PollCount", Mtype: pb.Metric_COUNTER, Value: 3},
+			wantType:  model.CounterType,
+			wantDelta: true,
+		},
+		{
+			name:      "Test 2",
+			in:        &pb.Metric{Id: "Alloc", Mtype: pb.Metric_GAUGE, Value: 0.5},
+			wantType:  model.GaugeType,
+			wantValue: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fromProto([]*pb.Metric{tt.in})
+			if err != nil {
+				t.Fatalf("fromProto() error = %v", err)
+			}
+			if got[0].MType != tt.wantType {
+				t.Errorf("fromProto() type = %s, want %s", got[0].MType, tt.wantType)
+			}
+			if (got[0].Delta != nil) != tt.wantDelta || (got[0].Value != nil) != tt.wantValue {
+				t.Errorf("fromProto() = %+v, want delta %v value %v", got[0], tt.wantDelta, tt.wantValue)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package grpcservice
+
+import (
+	"context"
+	"time"
+
+	"metrix/internal/controllers"
+	pb "metrix/internal/grpcapi/proto/v1"
+	pbv2 "metrix/internal/grpcapi/proto/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Health check services besides the metric services, the empty name is the overall server state.
+const (
+	HealthServiceLiveness  = "liveness"
+	HealthServiceReadiness = "readiness"
+	HealthServiceDatabase  = "database"
+)
+
+// healthWatchInterval - the interval of health state polling for Watch streams.
+const healthWatchInterval = time.Second
+
+// HealthServer - the implementation of grpc.health.v1.Health that reports the state
+// of the HealthController, the same as /liveness, /readiness and /ping do.
+type HealthServer struct {
+	healthpb.UnimplementedHealthServer
+	controller controllers.HealthController
+	interval   time.Duration
+}
+
+// NewHealthServer - the builder function for the HealthServer.
+func NewHealthServer(controller controllers.HealthController) *HealthServer {
+	return &HealthServer{
+		controller: controller,
+		interval:   healthWatchInterval,
+	}
+}
+
+// Check - the method that returns the serving status of the service.
+func (h *HealthServer) Check(
+	ctx context.Context,
+	in *healthpb.HealthCheckRequest,
+) (*healthpb.HealthCheckResponse, error) {
+	servingStatus, ok := h.status(in.GetService())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", in.GetService())
+	}
+
+	return &healthpb.HealthCheckResponse{Status: servingStatus}, nil
+}
+
+// Watch - the method that streams the serving status of the service whenever it changes.
+func (h *HealthServer) Watch(
+	in *healthpb.HealthCheckRequest,
+	stream grpc.ServerStreamingServer[healthpb.HealthCheckResponse],
+) error {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	first := true
+	for {
+		servingStatus, ok := h.status(in.GetService())
+		if !ok {
+			servingStatus = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+
+		if first || servingStatus != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: servingStatus}); err != nil {
+				return err
+			}
+			first, last = false, servingStatus
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (h *HealthServer) status(service string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	var serving bool
+	switch service {
+	case "", HealthServiceReadiness, pb.MetricService_ServiceDesc.ServiceName, pbv2.MetricService_ServiceDesc.ServiceName:
+		serving = h.controller.ReadinessState()
+	case HealthServiceLiveness:
+		serving = h.controller.LivenessState()
+	case HealthServiceDatabase:
+		serving = h.controller.PingDB()
+	default:
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, false
+	}
+
+	if serving {
+		return healthpb.HealthCheckResponse_SERVING, true
+	}
+
+	return healthpb.HealthCheckResponse_NOT_SERVING, true
+}
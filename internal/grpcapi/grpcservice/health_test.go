@@ -0,0 +1,175 @@
+package grpcservice
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"metrix/internal/storages"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeHealthController struct {
+	mux       sync.Mutex
+	readiness bool
+	liveness  bool
+	db        bool
+}
+
+func (f *fakeHealthController) SetReadiness(state bool) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.readiness = state
+}
+
+func (f *fakeHealthController) SetLiveness(state bool) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.liveness = state
+}
+
+func (f *fakeHealthController) ReadinessState() bool {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.readiness
+}
+
+func (f *fakeHealthController) LivenessState() bool {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.liveness
+}
+
+func (f *fakeHealthController) PingDB() bool {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.db
+}
+
+func newHealthTestConn(t *testing.T, controller *fakeHealthController) *grpc.ClientConn {
+	t.Helper()
+
+	ctx := context.Background()
+	repo := storages.NewInMemoryStorage(ctx, "", 0, false, 0, 1)
+	gs := NewGServiceServer(repo, storages.NewAgentMemoryStorage(), nil, controller)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := gs.NewServer(nil, nil, "")
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestHealthServer_Check(t *testing.T) {
+	controller := &fakeHealthController{readiness: false, liveness: true, db: true}
+	client := healthpb.NewHealthClient(newHealthTestConn(t, controller))
+
+	tests := []struct {
+		name    string
+		service string
+		want    healthpb.HealthCheckResponse_ServingStatus
+		code    codes.Code
+	}{
+		{name: "Test 1", service: "", want: healthpb.HealthCheckResponse_NOT_SERVING},
+		{name: "Test 2", service: HealthServiceLiveness, want: healthpb.HealthCheckResponse_SERVING},
+		{name: "Test 3", service: HealthServiceDatabase, want: healthpb.HealthCheckResponse_SERVING},
+		{name: "Test 4", service: "grpcapi.metrics.v2.MetricService", want: healthpb.HealthCheckResponse_NOT_SERVING},
+		{name: "Test 5", service: "unknown", code: codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: tt.service})
+			if status.Code(err) != tt.code {
+				t.Fatalf("Check() code = %v, want %v", status.Code(err), tt.code)
+			}
+			if err == nil && got.GetStatus() != tt.want {
+				t.Errorf("Check() = %v, want %v", got.GetStatus(), tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthServer_Watch(t *testing.T) {
+	controller := &fakeHealthController{}
+	client := healthpb.NewHealthClient(newHealthTestConn(t, controller))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: HealthServiceReadiness})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	got, err := stream.Recv()
+	if err != nil || got.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("first Recv() = %v, %v, want NOT_SERVING", got, err)
+	}
+
+	controller.SetReadiness(true)
+
+	got, err = stream.Recv()
+	if err != nil || got.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("second Recv() = %v, %v, want SERVING", got, err)
+	}
+}
+
+func TestReflection(t *testing.T) {
+	client := reflectionpb.NewServerReflectionClient(newHealthTestConn(t, &fakeHealthController{}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatalf("ServerReflectionInfo() error = %v", err)
+	}
+
+	err = stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+
+	services := map[string]bool{}
+	for _, s := range resp.GetListServicesResponse().GetService() {
+		services[s.GetName()] = true
+	}
+	for _, want := range []string{
+		"grpcapi.metrics.v1.MetricService",
+		"grpcapi.metrics.v2.MetricService",
+		"grpc.health.v1.Health",
+	} {
+		if !services[want] {
+			t.Errorf("service %s is not listed, got %v", want, services)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package grpcservice
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"metrix/internal/netpolicy"
+	"metrix/pkg/crypto"
+	"metrix/pkg/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// checkSubnet - the function that checks the client is not denied and belongs to the allowed
+// networks, the address is taken from the peer or, for peers being trusted proxies, from
+// the proxy headers in metadata.
+func checkSubnet(ctx context.Context, policy *netpolicy.Policy) error {
+	if !policy.Enabled() {
+		return nil
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return status.Error(codes.PermissionDenied, "Failed to get peer from context")
+	}
+
+	clientIP, err := policy.Check(func(name string) string {
+		return metadataValue(ctx, name)
+	}, p.Addr.String())
+	logger.Debug(ctx, fmt.Sprintf("request from ip %s", clientIP))
+	if err != nil {
+		logger.Warn(
+			ctx,
+			"request blocked by network policy",
+			"transport", "grpc",
+			"remote_addr", p.Addr.String(),
+			"client_ip", clientIP.String(),
+			"reason", err.Error(),
+		)
+		return status.Error(codes.PermissionDenied, "Access denied")
+	}
+
+	return nil
+}
+
+func subnetInterceptor(
+	policy *netpolicy.Policy,
+) func(context.Context, interface{}, *grpc.UnaryServerInfo, grpc.UnaryHandler) (interface{}, error) {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if err := checkSubnet(ctx, policy); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func subnetStreamInterceptor(
+	policy *netpolicy.Policy,
+) func(interface{}, grpc.ServerStream, *grpc.StreamServerInfo, grpc.StreamHandler) error {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := checkSubnet(ss.Context(), policy); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// loggingInterceptor - the interceptor that logs every call with its status and duration.
+func loggingInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+
+	logger.Info(
+		ctx,
+		"got incoming grpc request",
+		"method", info.FullMethod,
+		"code", status.Code(err).String(),
+		"duration", time.Since(start),
+	)
+
+	return resp, err
+}
+
+// loggingStreamInterceptor - the interceptor that logs every stream once it is finished.
+func loggingStreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	start := time.Now()
+
+	err := handler(srv, ss)
+
+	logger.Info(
+		ss.Context(),
+		"got incoming grpc stream",
+		"method", info.FullMethod,
+		"code", status.Code(err).String(),
+		"duration", time.Since(start),
+	)
+
+	return err
+}
+
+// signatureInterceptor - the interceptor that verifies HMAC-SHA256 signature of the request
+// message passed in metadata. As with the HTTP API unsigned requests are passed through.
+func signatureInterceptor(
+	signKey string,
+) func(context.Context, interface{}, *grpc.UnaryServerInfo, grpc.UnaryHandler) (interface{}, error) {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		signature := metadataValue(ctx, crypto.SignatureMetadataKey)
+		if signKey == "" || signature == "" {
+			return handler(ctx, req)
+		}
+
+		msg, ok := req.(proto.Message)
+		if !ok {
+			return nil, status.Error(codes.Internal, "failed to sign request")
+		}
+
+		payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to marshal request: %s", err)
+		}
+
+		if !crypto.VerifySignature(signKey, payload, signature) {
+			logger.Warn(ctx, fmt.Sprintf("wrong signature for %s", info.FullMethod))
+			return nil, status.Error(codes.InvalidArgument, "wrong signature")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// signatureStreamInterceptor - the interceptor that rejects signed streams. The signature
+// is passed once in metadata and cannot cover stream messages, so signing clients have
+// to use unary calls.
+func signatureStreamInterceptor(
+	signKey string,
+) func(interface{}, grpc.ServerStream, *grpc.StreamServerInfo, grpc.StreamHandler) error {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if signKey != "" && metadataValue(ss.Context(), crypto.SignatureMetadataKey) != "" {
+			return status.Error(codes.InvalidArgument, "signed streams are not supported, use unary calls")
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// compressionInterceptor - the interceptor that compresses responses with gzip
+// for clients that accept it.
+func compressionInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	setSendCompressor(ctx)
+
+	return handler(ctx, req)
+}
+
+// compressionStreamInterceptor - the interceptor that compresses stream messages with gzip
+// for clients that accept it.
+func compressionStreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	setSendCompressor(ss.Context())
+
+	return handler(srv, ss)
+}
+
+func setSendCompressor(ctx context.Context) {
+	supported, err := grpc.ClientSupportedCompressors(ctx)
+	if err != nil || !slices.Contains(supported, gzip.Name) {
+		return
+	}
+
+	if err := grpc.SetSendCompressor(ctx, gzip.Name); err != nil {
+		logger.Warn(ctx, fmt.Sprintf("failed to set gzip compressor: %s", err))
+	}
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
@@ -0,0 +1,154 @@
+package grpcservice
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	pb "metrix/internal/grpcapi/proto/v1"
+	"metrix/internal/netpolicy"
+	"metrix/pkg/crypto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSignatureInterceptor(t *testing.T) {
+	const signKey = "secret"
+	client, _ := newSignedTestClient(t, signKey)
+
+	request := &pb.MetricsRequest{Items: []*pb.Metric{{Id: "PollCount", Mtype: pb.Metric_COUNTER, Value: 1}}}
+	payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(request)
+	if err != nil {
+		t.Fatalf("failed to marshal request: %s", err)
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		code      codes.Code
+	}{
+		{name: "Test 1", signature: crypto.Sign(signKey, payload), code: codes.OK},
+		{name: "Test 2", signature: crypto.Sign("other", payload), code: codes.InvalidArgument},
+		{name: "Test 3", signature: "", code: codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.signature != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, crypto.SignatureMetadataKey, tt.signature)
+			}
+
+			_, err := client.SetMetrics(ctx, request)
+			if status.Code(err) != tt.code {
+				t.Errorf("SetMetrics() code = %v, want %v", status.Code(err), tt.code)
+			}
+		})
+	}
+
+	t.Run("Test 4", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ctx = metadata.AppendToOutgoingContext(ctx, crypto.SignatureMetadataKey, crypto.Sign(signKey, payload))
+
+		stream, err := client.StreamMetrics(ctx)
+		if err != nil {
+			t.Fatalf("StreamMetrics() error = %v", err)
+		}
+		if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Recv() code = %v, want InvalidArgument", status.Code(err))
+		}
+	})
+}
+
+// compressionRecorder - the client stats handler that records compression of responses.
+type compressionRecorder struct {
+	mux         sync.Mutex
+	compression string
+}
+
+func (r *compressionRecorder) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (r *compressionRecorder) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if h, ok := s.(*stats.InHeader); ok {
+		r.mux.Lock()
+		r.compression = h.Compression
+		r.mux.Unlock()
+	}
+}
+
+func (r *compressionRecorder) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (r *compressionRecorder) HandleConn(context.Context, stats.ConnStats) {}
+
+func TestCompressionInterceptor(t *testing.T) {
+	recorder := &compressionRecorder{}
+	client, _ := newSignedTestClient(t, "", grpc.WithStatsHandler(recorder))
+
+	// the request is not compressed, gzip is only advertised as accepted
+	_, err := client.ListMetrics(context.Background(), &pb.ListMetricsRequest{})
+	if err != nil {
+		t.Fatalf("ListMetrics() error = %v", err)
+	}
+
+	recorder.mux.Lock()
+	defer recorder.mux.Unlock()
+	if recorder.compression != gzip.Name {
+		t.Errorf("response compression = %q, want %q", recorder.compression, gzip.Name)
+	}
+}
+
+func TestCheckSubnet(t *testing.T) {
+	policy, err := netpolicy.Parse("192.168.1.0/24,2001:db8::/32", "192.168.1.66", "10.0.0.1", "X-Real-IP")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		addr net.Addr
+		md   metadata.MD
+		code codes.Code
+	}{
+		{name: "Test 1", addr: &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 5000}, code: codes.OK},
+		{name: "Test 2", addr: &net.TCPAddr{IP: net.ParseIP("2001:db9::1"), Port: 5000}, code: codes.PermissionDenied},
+		{name: "Test 3", addr: &net.TCPAddr{IP: net.ParseIP("192.168.1.7"), Port: 5000}, code: codes.OK},
+		{
+			name: "Test 4",
+			addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5000},
+			md:   metadata.Pairs("x-real-ip", "192.168.1.7"),
+			code: codes.PermissionDenied,
+		},
+		{name: "Test 5", addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5000}, code: codes.PermissionDenied},
+		{name: "Test 6", addr: &net.TCPAddr{IP: net.ParseIP("192.168.1.66"), Port: 5000}, code: codes.PermissionDenied},
+		{
+			name: "Test 7",
+			addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000},
+			md:   metadata.Pairs("x-real-ip", "192.168.1.7"),
+			code: codes.OK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tt.addr})
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			if err := checkSubnet(ctx, policy); status.Code(err) != tt.code {
+				t.Errorf("checkSubnet() = %v, want %v", err, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package grpcservice
+
+import (
+	"context"
+	"encoding/base64"
+	"sort"
+	"strings"
+
+	pb "metrix/internal/grpcapi/proto/v1"
+	"metrix/internal/model"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultPageSize - the number of metrics returned by ListMetrics when page size is not set.
+const defaultPageSize = 100
+
+// maxPageSize - the maximum number of metrics returned by a single ListMetrics call.
+const maxPageSize = 1000
+
+// watchBuffer - the number of updates buffered for a WatchMetrics stream.
+const watchBuffer = 256
+
+// GetMetric - the method that returns a single metric series by ID and labels.
+func (gs *GServiceServer) GetMetric(
+	ctx context.Context,
+	in *pb.GetMetricRequest,
+) (*pb.Metric, error) {
+	metric, err := gs.getMetric(ctx, in.GetId(), in.GetLabels())
+	if err != nil {
+		return nil, err
+	}
+
+	return toProto(metric), nil
+}
+
+// ListMetrics - the method that returns metrics ordered by series key. The prefix filters
+// series keys, which start with the metric ID. The page token is opaque and is returned
+// as the next page token while more metrics are available.
+func (gs *GServiceServer) ListMetrics(
+	ctx context.Context,
+	in *pb.ListMetricsRequest,
+) (*pb.ListMetricsResponse, error) {
+	metrics, next, err := gs.listMetrics(ctx, in.GetPrefix(), in.GetPageSize(), in.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.ListMetricsResponse{NextPageToken: next}
+	for i := range metrics {
+		resp.Items = append(resp.Items, toProto(&metrics[i]))
+	}
+
+	return resp, nil
+}
+
+// WatchMetrics - the method that streams metrics with matching ID prefix as they are stored.
+func (gs *GServiceServer) WatchMetrics(
+	in *pb.WatchMetricsRequest,
+	stream grpc.ServerStreamingServer[pb.Metric],
+) error {
+	return gs.watchMetrics(stream.Context(), in.GetPrefix(), func(m *model.Metric) error {
+		return stream.Send(toProto(m))
+	})
+}
+
+func (gs *GServiceServer) getMetric(
+	ctx context.Context,
+	id string,
+	labels map[string]string,
+) (*model.Metric, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "metric id is required")
+	}
+
+	metric, err := gs.Repository.Read(ctx, model.SeriesKey(id, labels))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read metric: %s", err)
+	}
+
+	if metric == nil {
+		return nil, status.Error(codes.NotFound, "metric not found")
+	}
+
+	return metric, nil
+}
+
+func (gs *GServiceServer) listMetrics(
+	ctx context.Context,
+	prefix string,
+	size int32,
+	pageToken string,
+) ([]model.Metric, string, error) {
+	pageSize := int(size)
+	switch {
+	case pageSize < 0:
+		return nil, "", status.Error(codes.InvalidArgument, "page size must not be negative")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+
+	after := ""
+	if pageToken != "" {
+		token, err := base64.RawURLEncoding.DecodeString(pageToken)
+		if err != nil {
+			return nil, "", status.Error(codes.InvalidArgument, "malformed page token")
+		}
+		after = string(token)
+	}
+
+	ids, err := gs.Repository.ReadIDs(ctx)
+	if err != nil {
+		return nil, "", status.Errorf(codes.Internal, "failed to read metric ids: %s", err)
+	}
+
+	keys := []string{}
+	if ids != nil {
+		for _, key := range *ids {
+			if strings.HasPrefix(key, prefix) && key > after {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	next := ""
+	if len(keys) > pageSize {
+		keys = keys[:pageSize]
+		next = base64.RawURLEncoding.EncodeToString([]byte(keys[len(keys)-1]))
+	}
+
+	if len(keys) == 0 {
+		return nil, next, nil
+	}
+
+	metrics, err := gs.Repository.ReadMany(ctx, keys)
+	if err != nil {
+		return nil, "", status.Errorf(codes.Internal, "failed to read metrics: %s", err)
+	}
+
+	sort.Slice(*metrics, func(i, j int) bool {
+		return (*metrics)[i].SeriesKey() < (*metrics)[j].SeriesKey()
+	})
+
+	return *metrics, next, nil
+}
+
+func (gs *GServiceServer) watchMetrics(
+	ctx context.Context,
+	prefix string,
+	send func(m *model.Metric) error,
+) error {
+	if gs.Updates == nil {
+		return status.Error(codes.Unimplemented, "metric updates are not available")
+	}
+
+	updates, cancel := gs.Updates.Subscribe(watchBuffer)
+	defer cancel()
+
+	for {
+		select {
+		case metric, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			if !strings.HasPrefix(metric.ID, prefix) {
+				continue
+			}
+			if err := send(&metric); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// toProto - the function that converts a metric to its protobuf representation.
+func toProto(m *model.Metric) *pb.Metric {
+	out := &pb.Metric{
+		Id:     m.ID,
+		Labels: m.Labels,
+	}
+
+	switch m.MType {
+	case model.CounterType:
+		out.Mtype = pb.Metric_COUNTER
+		if m.Delta != nil {
+			out.Value = float32(*m.Delta)
+		}
+	case model.GaugeType:
+		out.Mtype = pb.Metric_GAUGE
+		if m.Value != nil {
+			out.Value = float32(*m.Value)
+		}
+	case model.HistogramType:
+		out.Mtype = pb.Metric_HISTOGRAM
+		if m.Histogram != nil {
+			out.Histogram = &pb.Histogram{
+				Bounds: m.Histogram.Bounds,
+				Counts: m.Histogram.Counts,
+				Sum:    m.Histogram.Sum,
+				Count:  m.Histogram.Count,
+			}
+		}
+	case model.SummaryType:
+		out.Mtype = pb.Metric_SUMMARY
+		if m.Summary != nil && m.Summary.Sketch != nil {
+			out.Summary = &pb.Summary{
+				Sum:       m.Summary.Sketch.Sum,
+				Count:     m.Summary.Sketch.Count,
+				Quantiles: map[string]float64{},
+			}
+			if summary, err := m.Summary.WithQuantiles(nil); err == nil {
+				out.Summary.Quantiles = summary.Quantiles
+			}
+		}
+	}
+
+	return out
+}
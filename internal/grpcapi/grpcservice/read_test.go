@@ -0,0 +1,222 @@
+package grpcservice
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "metrix/internal/grpcapi/proto/v1"
+	"metrix/internal/model"
+	"metrix/internal/repository"
+	"metrix/internal/storages"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestClient(t *testing.T) (pb.MetricServiceClient, *repository.BroadcastRepository) {
+	t.Helper()
+
+	return newSignedTestClient(t, "")
+}
+
+func newSignedTestClient(
+	t *testing.T,
+	signKey string,
+	opts ...grpc.DialOption,
+) (pb.MetricServiceClient, *repository.BroadcastRepository) {
+	t.Helper()
+
+	ctx := context.Background()
+	updates := repository.NewBroadcastRepository(storages.NewInMemoryStorage(ctx, "", 0, false, 0, 1))
+	gs := NewGServiceServer(updates, storages.NewAgentMemoryStorage(), updates, nil)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := gs.NewServer(nil, nil, signKey)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	opts = append(
+		opts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return pb.NewMetricServiceClient(conn), updates
+}
+
+func seed(t *testing.T, repo repository.MetricRepository, ids ...string) {
+	t.Helper()
+
+	metrics := make([]model.Metric, 0, len(ids))
+	for i, id := range ids {
+		delta := int64(i + 1)
+		metrics = append(metrics, model.Metric{ID: id, MType: model.CounterType, Delta: &delta})
+	}
+	if _, err := repo.UpsertMany(context.Background(), metrics); err != nil {
+		t.Fatalf("failed to seed metrics: %v", err)
+	}
+}
+
+func TestGServiceServer_GetMetric(t *testing.T) {
+	client, repo := newTestClient(t)
+
+	value := float64(42.5)
+	metric := &model.Metric{
+		ID:     "Alloc",
+		MType:  model.GaugeType,
+		Labels: model.Labels{"host": "h1"},
+		Value:  &value,
+	}
+	if _, err := repo.Create(context.Background(), metric); err != nil {
+		t.Fatalf("failed to create metric: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		in   *pb.GetMetricRequest
+		want *pb.Metric
+		code codes.Code
+	}{
+		{
+			name: "Test 1",
+			in:   &pb.GetMetricRequest{Id: "Alloc", Labels: map[string]string{"host": "h1"}},
+			want: &pb.Metric{
+				Id:     "Alloc",
+				Mtype:  pb.Metric_GAUGE,
+				Value:  42.5,
+				Labels: map[string]string{"host": "h1"},
+			},
+			code: codes.OK,
+		},
+		{
+			name: "Test 2",
+			in:   &pb.GetMetricRequest{Id: "Alloc"},
+			code: codes.NotFound,
+		},
+		{
+			name: "Test 3",
+			in:   &pb.GetMetricRequest{},
+			code: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := client.GetMetric(context.Background(), tt.in)
+			if status.Code(err) != tt.code {
+				t.Fatalf("GetMetric() code = %v, want %v", status.Code(err), tt.code)
+			}
+			if tt.want == nil {
+				return
+			}
+			if got.GetId() != tt.want.GetId() ||
+				got.GetMtype() != tt.want.GetMtype() ||
+				got.GetValue() != tt.want.GetValue() ||
+				got.GetLabels()["host"] != tt.want.GetLabels()["host"] {
+				t.Errorf("GetMetric() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGServiceServer_ListMetrics(t *testing.T) {
+	client, repo := newTestClient(t)
+	seed(t, repo, "cpu_user", "cpu_system", "cpu_idle", "mem_free")
+
+	ctx := context.Background()
+
+	resp, err := client.ListMetrics(ctx, &pb.ListMetricsRequest{Prefix: "cpu_", PageSize: 2})
+	if err != nil {
+		t.Fatalf("ListMetrics() error = %v", err)
+	}
+	if ids := metricIDs(resp.GetItems()); len(ids) != 2 || ids[0] != "cpu_idle" || ids[1] != "cpu_system" {
+		t.Errorf("first page = %v, want [cpu_idle cpu_system]", ids)
+	}
+	if resp.GetNextPageToken() == "" {
+		t.Fatalf("next page token must be set")
+	}
+
+	resp, err = client.ListMetrics(ctx, &pb.ListMetricsRequest{
+		Prefix:    "cpu_",
+		PageSize:  2,
+		PageToken: resp.GetNextPageToken(),
+	})
+	if err != nil {
+		t.Fatalf("ListMetrics() error = %v", err)
+	}
+	if ids := metricIDs(resp.GetItems()); len(ids) != 1 || ids[0] != "cpu_user" {
+		t.Errorf("second page = %v, want [cpu_user]", ids)
+	}
+	if resp.GetNextPageToken() != "" {
+		t.Errorf("next page token = %q, want empty", resp.GetNextPageToken())
+	}
+
+	_, err = client.ListMetrics(ctx, &pb.ListMetricsRequest{PageSize: -1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ListMetrics() code = %v, want InvalidArgument", status.Code(err))
+	}
+
+	_, err = client.ListMetrics(ctx, &pb.ListMetricsRequest{PageToken: "%%%"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ListMetrics() code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestGServiceServer_WatchMetrics(t *testing.T) {
+	client, repo := newTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchMetrics(ctx, &pb.WatchMetricsRequest{Prefix: "cpu_"})
+	if err != nil {
+		t.Fatalf("WatchMetrics() error = %v", err)
+	}
+
+	// the subscription is registered asynchronously, keep publishing until it is observed
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				delta := int64(1)
+				_, _ = repo.UpsertMany(context.Background(), []model.Metric{
+					{ID: "mem_free", MType: model.CounterType, Delta: &delta},
+					{ID: "cpu_user", MType: model.CounterType, Delta: &delta},
+				})
+			}
+		}
+	}()
+
+	got, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if got.GetId() != "cpu_user" || got.GetMtype() != pb.Metric_COUNTER {
+		t.Errorf("Recv() = %v, want cpu_user counter", got)
+	}
+}
+
+func metricIDs(items []*pb.Metric) []string {
+	ids := make([]string, 0, len(items))
+	for _, m := range items {
+		ids = append(ids, m.GetId())
+	}
+	return ids
+}
@@ -0,0 +1,163 @@
+package grpcservice
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	pb "metrix/internal/grpcapi/proto/v1"
+	"metrix/internal/model"
+	"metrix/pkg/logger"
+
+	"google.golang.org/grpc"
+)
+
+// streamBatchSize - the number of metrics that triggers an immediate flush of a stream batch.
+const streamBatchSize = 1000
+
+// streamFlushInterval - the maximum time received metrics wait before they are persisted.
+const streamFlushInterval = 200 * time.Millisecond
+
+// streamBatch - the structure that accumulates received batches until they are persisted.
+type streamBatch struct {
+	seqs    []uint64
+	counts  []int32
+	metrics []model.Metric
+}
+
+func (b *streamBatch) add(seq uint64, metrics []model.Metric) {
+	b.seqs = append(b.seqs, seq)
+	b.counts = append(b.counts, int32(len(metrics)))
+	b.metrics = append(b.metrics, metrics...)
+}
+
+func (b *streamBatch) reset() {
+	b.seqs = nil
+	b.counts = nil
+	b.metrics = nil
+}
+
+// StreamMetrics - the method that receives metric batches over a long-lived stream.
+// Batches are merged and persisted with a single UpsertMany once enough metrics are
+// collected or the flush interval passes, then every batch is acknowledged by its sequence.
+func (gs *GServiceServer) StreamMetrics(
+	stream grpc.BidiStreamingServer[pb.MetricsBatch, pb.MetricsAck],
+) error {
+	return serveStream(
+		gs,
+		stream,
+		func(in *pb.MetricsBatch) (uint64, []model.Metric, error) {
+			metrics, err := fromProto(in.GetItems())
+			return in.GetSeq(), metrics, err
+		},
+		func(seq uint64, stored bool, message string, accepted int32) *pb.MetricsAck {
+			return &pb.MetricsAck{Seq: seq, Status: stored, Message: message, Accepted: accepted}
+		},
+	)
+}
+
+// serveStream - the function that implements StreamMetrics for any API version, decode converts
+// a received batch to the model and ack builds the acknowledgement of a batch.
+func serveStream[In any, Out any](
+	gs *GServiceServer,
+	stream grpc.BidiStreamingServer[In, Out],
+	decode func(in *In) (uint64, []model.Metric, error),
+	ack func(seq uint64, stored bool, message string, accepted int32) *Out,
+) error {
+	ctx := stream.Context()
+
+	agent, err := agentFromMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	batches := make(chan *In)
+	recvErr := make(chan error, 1)
+	go func() {
+		defer close(batches)
+		for {
+			in, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+
+			select {
+			case batches <- in:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(streamFlushInterval)
+	defer ticker.Stop()
+
+	pending := &streamBatch{}
+	flush := func() error {
+		if len(pending.seqs) == 0 {
+			return nil
+		}
+		defer pending.reset()
+
+		stored, message := true, "metrics accepted"
+		if err := gs.store(ctx, agent, pending.metrics); err != nil {
+			logger.Warn(ctx, fmt.Sprintf("failed to store streamed metrics: %s", err))
+			stored, message = false, err.Error()
+		}
+
+		for i, seq := range pending.seqs {
+			accepted := int32(0)
+			if stored {
+				accepted = pending.counts[i]
+			}
+			if err := stream.Send(ack(seq, stored, message, accepted)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for {
+		select {
+		case in, ok := <-batches:
+			if !ok {
+				if err := flush(); err != nil {
+					return err
+				}
+
+				select {
+				case err := <-recvErr:
+					if errors.Is(err, io.EOF) {
+						return nil
+					}
+					return err
+				default:
+					return ctx.Err()
+				}
+			}
+
+			seq, metrics, err := decode(in)
+			if err != nil {
+				if err := stream.Send(ack(seq, false, err.Error(), 0)); err != nil {
+					return err
+				}
+				continue
+			}
+
+			pending.add(seq, metrics)
+
+			if len(pending.metrics) >= streamBatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		case <-ticker.C:
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
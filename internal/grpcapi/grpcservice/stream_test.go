@@ -0,0 +1,77 @@
+package grpcservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "metrix/internal/grpcapi/proto/v1"
+	"metrix/internal/model"
+)
+
+func TestGServiceServer_StreamMetrics(t *testing.T) {
+	client, repo := newTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamMetrics(ctx)
+	if err != nil {
+		t.Fatalf("StreamMetrics() error = %v", err)
+	}
+
+	batches := []*pb.MetricsBatch{
+		{Seq: 1, Items: []*pb.Metric{
+			{Id: "PollCount", Mtype: pb.Metric_COUNTER, Value: 3},
+			{Id: "PollCount", Mtype: pb.Metric_COUNTER, Value: 2},
+		}},
+		{Seq: 2, Items: []*pb.Metric{
+			{Id: "Alloc", Mtype: pb.Metric_COUNTER, Labels: map[string]string{"bad-name": "x"}},
+		}},
+		{Seq: 3, Items: []*pb.Metric{
+			{Id: "Frees", Mtype: pb.Metric_COUNTER, Value: 7},
+		}},
+	}
+	for _, b := range batches {
+		if err := stream.Send(b); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	acks := map[uint64]*pb.MetricsAck{}
+	for len(acks) < len(batches) {
+		ack, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		acks[ack.GetSeq()] = ack
+	}
+
+	tests := []struct {
+		seq      uint64
+		status   bool
+		accepted int32
+	}{
+		{seq: 1, status: true, accepted: 2},
+		{seq: 2, status: false, accepted: 0},
+		{seq: 3, status: true, accepted: 1},
+	}
+	for _, tt := range tests {
+		ack := acks[tt.seq]
+		if ack.GetStatus() != tt.status || ack.GetAccepted() != tt.accepted {
+			t.Errorf("ack %d = %v, want status=%v accepted=%d", tt.seq, ack, tt.status, tt.accepted)
+		}
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("CloseSend() error = %v", err)
+	}
+
+	metric, err := repo.Read(ctx, model.SeriesKey("PollCount", nil))
+	if err != nil || metric == nil {
+		t.Fatalf("Read() = %v, %v", metric, err)
+	}
+	if *metric.Delta != 5 {
+		t.Errorf("PollCount delta = %d, want 5", *metric.Delta)
+	}
+}
@@ -0,0 +1,109 @@
+package grpcservice
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+
+	pb "metrix/internal/grpcapi/proto/v1"
+	"metrix/internal/identity"
+	"metrix/internal/model"
+	"metrix/internal/storages"
+	"metrix/pkg/crypto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestGServiceServer_MutualTLS(t *testing.T) {
+	ca, err := crypto.NewCertificateAuthority("test ca", time.Hour)
+	if err != nil {
+		t.Fatalf("NewCertificateAuthority() error = %s", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Cert)
+
+	serverPEM, serverKey, err := ca.IssueServer("localhost", []string{"127.0.0.1"}, time.Hour)
+	if err != nil {
+		t.Fatalf("IssueServer() error = %s", err)
+	}
+	serverCert, err := tls.X509KeyPair(serverPEM, serverKey)
+	if err != nil {
+		t.Fatalf("X509KeyPair() error = %s", err)
+	}
+
+	clientPEM, clientKey, err := ca.IssueClient("agent-1", time.Hour)
+	if err != nil {
+		t.Fatalf("IssueClient() error = %s", err)
+	}
+	clientCert, err := tls.X509KeyPair(clientPEM, clientKey)
+	if err != nil {
+		t.Fatalf("X509KeyPair() error = %s", err)
+	}
+
+	ctx := context.Background()
+	repo := storages.NewInMemoryStorage(ctx, "", 0, false, 0, 1)
+	gs := NewGServiceServer(repo, storages.NewAgentMemoryStorage(), nil, nil)
+
+	server := gs.NewServer(nil, &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, "")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(
+		credentials.NewTLS(&tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			RootCAs:      pool,
+			Certificates: []tls.Certificate{clientCert},
+		}),
+	))
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+	defer conn.Close()
+	client := pb.NewMetricServiceClient(conn)
+
+	request := &pb.MetricsRequest{Items: []*pb.Metric{{Id: "PollCount", Mtype: pb.Metric_COUNTER, Value: 1}}}
+
+	tests := []struct {
+		name    string
+		agentID string
+		code    codes.Code
+	}{
+		{name: "Test 1", agentID: "", code: codes.OK},
+		{name: "Test 2", agentID: "agent-1", code: codes.OK},
+		{name: "Test 3", agentID: "agent-2", code: codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callCtx := ctx
+			if tt.agentID != "" {
+				callCtx = metadata.AppendToOutgoingContext(ctx, identity.AgentIDMetadataKey, tt.agentID)
+			}
+
+			_, err := client.SetMetrics(callCtx, request)
+			if status.Code(err) != tt.code {
+				t.Fatalf("SetMetrics() code = %v, want %v", status.Code(err), tt.code)
+			}
+		})
+	}
+
+	metric, err := repo.Read(ctx, model.SeriesKey("PollCount", model.Labels{model.AgentLabel: "agent-1"}))
+	if err != nil || metric == nil {
+		t.Errorf("metric must be attributed to the certificate identity: %v, %v", metric, err)
+	}
+}
@@ -0,0 +1,252 @@
+package grpcservice
+
+import (
+	"context"
+
+	pbv2 "metrix/internal/grpcapi/proto/v2"
+	"metrix/internal/model"
+	"metrix/pkg/sketch"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GServiceServerV2 - the structure that serves the v2 MetricService on top of GServiceServer,
+// v2 transfers counters as int64 and gauges as double without precision loss.
+type GServiceServerV2 struct {
+	pbv2.UnimplementedMetricServiceServer
+	gs *GServiceServer
+}
+
+// V2 - the method that returns the v2 API of the server.
+func (gs *GServiceServer) V2() *GServiceServerV2 {
+	return &GServiceServerV2{gs: gs}
+}
+
+func (s *GServiceServerV2) SetMetrics(
+	ctx context.Context,
+	in *pbv2.MetricsRequest,
+) (*pbv2.MetricsResponse, error) {
+	agent, err := agentFromMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := fromProtoV2(in.GetItems())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.gs.store(ctx, agent, metrics); err != nil {
+		return nil, err
+	}
+
+	return &pbv2.MetricsResponse{
+		Status:  true,
+		Message: "metrics accepted",
+	}, nil
+}
+
+// GetMetric - the method that returns a single metric series by ID and labels.
+func (s *GServiceServerV2) GetMetric(
+	ctx context.Context,
+	in *pbv2.GetMetricRequest,
+) (*pbv2.Metric, error) {
+	metric, err := s.gs.getMetric(ctx, in.GetId(), in.GetLabels())
+	if err != nil {
+		return nil, err
+	}
+
+	return toProtoV2(metric), nil
+}
+
+// ListMetrics - the method that returns metrics ordered by series key.
+func (s *GServiceServerV2) ListMetrics(
+	ctx context.Context,
+	in *pbv2.ListMetricsRequest,
+) (*pbv2.ListMetricsResponse, error) {
+	metrics, next, err := s.gs.listMetrics(ctx, in.GetPrefix(), in.GetPageSize(), in.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pbv2.ListMetricsResponse{NextPageToken: next}
+	for i := range metrics {
+		resp.Items = append(resp.Items, toProtoV2(&metrics[i]))
+	}
+
+	return resp, nil
+}
+
+// WatchMetrics - the method that streams metrics with matching ID prefix as they are stored.
+func (s *GServiceServerV2) WatchMetrics(
+	in *pbv2.WatchMetricsRequest,
+	stream grpc.ServerStreamingServer[pbv2.Metric],
+) error {
+	return s.gs.watchMetrics(stream.Context(), in.GetPrefix(), func(m *model.Metric) error {
+		return stream.Send(toProtoV2(m))
+	})
+}
+
+// StreamMetrics - the method that receives metric batches over a long-lived stream.
+func (s *GServiceServerV2) StreamMetrics(
+	stream grpc.BidiStreamingServer[pbv2.MetricsBatch, pbv2.MetricsAck],
+) error {
+	return serveStream(
+		s.gs,
+		stream,
+		func(in *pbv2.MetricsBatch) (uint64, []model.Metric, error) {
+			metrics, err := fromProtoV2(in.GetItems())
+			return in.GetSeq(), metrics, err
+		},
+		func(seq uint64, stored bool, message string, accepted int32) *pbv2.MetricsAck {
+			return &pbv2.MetricsAck{Seq: seq, Status: stored, Message: message, Accepted: accepted}
+		},
+	)
+}
+
+// fromProtoV2 - the function that converts v2 protobuf metrics to the model,
+// the payload must match the metric type.
+func fromProtoV2(items []*pbv2.Metric) ([]model.Metric, error) {
+	metrics := []model.Metric{}
+
+	for _, m := range items {
+		metric := model.Metric{ID: m.GetId()}
+
+		if len(m.GetLabels()) > 0 {
+			metric.Labels = model.Labels(m.GetLabels())
+			if err := metric.Labels.Validate(); err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+		}
+
+		switch payload := m.GetPayload().(type) {
+		case *pbv2.Metric_Delta:
+			if m.GetMtype() != pbv2.Metric_COUNTER {
+				return nil, status.Errorf(codes.InvalidArgument, "metric %s: delta is only valid for counters", m.GetId())
+			}
+			delta := payload.Delta
+			metric.MType = model.CounterType
+			metric.Delta = &delta
+		case *pbv2.Metric_Value:
+			if m.GetMtype() != pbv2.Metric_GAUGE {
+				return nil, status.Errorf(codes.InvalidArgument, "metric %s: value is only valid for gauges", m.GetId())
+			}
+			value := payload.Value
+			metric.MType = model.GaugeType
+			metric.Value = &value
+		case *pbv2.Metric_Histogram:
+			if m.GetMtype() != pbv2.Metric_HISTOGRAM {
+				return nil, status.Errorf(codes.InvalidArgument, "metric %s: histogram is only valid for histograms", m.GetId())
+			}
+			h := payload.Histogram
+			metric.MType = model.HistogramType
+			metric.Histogram = &model.Histogram{
+				Bounds: h.GetBounds(),
+				Counts: h.GetCounts(),
+				Sum:    h.GetSum(),
+				Count:  h.GetCount(),
+			}
+			if err := metric.Histogram.Validate(); err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+		case *pbv2.Metric_Summary:
+			if m.GetMtype() != pbv2.Metric_SUMMARY {
+				return nil, status.Errorf(codes.InvalidArgument, "metric %s: summary is only valid for summaries", m.GetId())
+			}
+			metric.MType = model.SummaryType
+			metric.Summary = &model.Summary{Sketch: fromProtoSketch(payload.Summary)}
+			if err := metric.Summary.Validate(); err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "metric %s: unsupported payload", m.GetId())
+		}
+
+		metrics = append(metrics, metric)
+	}
+
+	return metrics, nil
+}
+
+// toProtoV2 - the function that converts a metric to its v2 protobuf representation.
+func toProtoV2(m *model.Metric) *pbv2.Metric {
+	out := &pbv2.Metric{
+		Id:     m.ID,
+		Labels: m.Labels,
+	}
+
+	switch m.MType {
+	case model.CounterType:
+		out.Mtype = pbv2.Metric_COUNTER
+		if m.Delta != nil {
+			out.Payload = &pbv2.Metric_Delta{Delta: *m.Delta}
+		}
+	case model.GaugeType:
+		out.Mtype = pbv2.Metric_GAUGE
+		if m.Value != nil {
+			out.Payload = &pbv2.Metric_Value{Value: *m.Value}
+		}
+	case model.HistogramType:
+		out.Mtype = pbv2.Metric_HISTOGRAM
+		if m.Histogram != nil {
+			out.Payload = &pbv2.Metric_Histogram{Histogram: &pbv2.Histogram{
+				Bounds: m.Histogram.Bounds,
+				Counts: m.Histogram.Counts,
+				Sum:    m.Histogram.Sum,
+				Count:  m.Histogram.Count,
+			}}
+		}
+	case model.SummaryType:
+		out.Mtype = pbv2.Metric_SUMMARY
+		if m.Summary != nil && m.Summary.Sketch != nil {
+			summary := &pbv2.Summary{
+				Sum:       m.Summary.Sketch.Sum,
+				Count:     m.Summary.Sketch.Count,
+				Quantiles: map[string]float64{},
+				Sketch:    toProtoSketch(m.Summary.Sketch),
+			}
+			if withQuantiles, err := m.Summary.WithQuantiles(nil); err == nil {
+				summary.Quantiles = withQuantiles.Quantiles
+			}
+			out.Payload = &pbv2.Metric_Summary{Summary: summary}
+		}
+	}
+
+	return out
+}
+
+// fromProtoSketch - the function that converts the sketch of a v2 protobuf summary,
+// nil is returned when the summary carries no sketch.
+func fromProtoSketch(summary *pbv2.Summary) *sketch.DDSketch {
+	in := summary.GetSketch()
+	if in == nil {
+		return nil
+	}
+
+	return &sketch.DDSketch{
+		Alpha:    in.GetAlpha(),
+		MaxBins:  int(in.GetMaxBins()),
+		Positive: in.GetPositive(),
+		Negative: in.GetNegative(),
+		Zero:     in.GetZero(),
+		Count:    summary.GetCount(),
+		Sum:      summary.GetSum(),
+		Min:      in.GetMin(),
+		Max:      in.GetMax(),
+	}
+}
+
+// toProtoSketch - the function that converts a sketch to its v2 protobuf representation.
+func toProtoSketch(s *sketch.DDSketch) *pbv2.Sketch {
+	return &pbv2.Sketch{
+		Alpha:    s.Alpha,
+		MaxBins:  int32(s.MaxBins),
+		Positive: s.Positive,
+		Negative: s.Negative,
+		Zero:     s.Zero,
+		Min:      s.Min,
+		Max:      s.Max,
+	}
+}
@@ -0,0 +1,127 @@
+package grpcservice
+
+import (
+	"testing"
+
+	pbv2 "metrix/internal/grpcapi/proto/v2"
+	"metrix/internal/model"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFromProtoV2(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *pbv2.Metric
+		code codes.Code
+	}{
+		{
+			name: "Test 1",
+			in:   &pbv2.Metric{Id: "TotalAlloc", Mtype: pbv2.Metric_COUNTER, Payload: &pbv2.Metric_Delta{Delta: 1<<53 + 1}},
+			code: codes.OK,
+		},
+		{
+			name: "Test 2",
+			in:   &pbv2.Metric{Id: "Alloc", Mtype: pbv2.Metric_GAUGE, Payload: &pbv2.Metric_Value{Value: 0.1}},
+			code: codes.OK,
+		},
+		{
+			name: "Test 3",
+			in:   &pbv2.Metric{Id: "Alloc", Mtype: pbv2.Metric_GAUGE, Payload: &pbv2.Metric_Delta{Delta: 1}},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "Test 4",
+			in:   &pbv2.Metric{Id: "Alloc", Mtype: pbv2.Metric_COUNTER},
+			code: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fromProtoV2([]*pbv2.Metric{tt.in})
+			if status.Code(err) != tt.code {
+				t.Fatalf("fromProtoV2() code = %v, want %v", status.Code(err), tt.code)
+			}
+			if err != nil {
+				return
+			}
+
+			back := toProtoV2(&got[0])
+			if back.GetMtype() != tt.in.GetMtype() ||
+				back.GetDelta() != tt.in.GetDelta() ||
+				back.GetValue() != tt.in.GetValue() {
+				t.Errorf("round trip = %v, want %v", back, tt.in)
+			}
+		})
+	}
+}
+
+func TestToProtoV2_Summary(t *testing.T) {
+	summary := model.NewSummary().Observe(1).Observe(2).Observe(3)
+	got := toProtoV2(&model.Metric{ID: "latency", MType: model.SummaryType, Summary: summary})
+
+	if got.GetMtype() != pbv2.Metric_SUMMARY || got.GetSummary().GetCount() != 3 {
+		t.Fatalf("toProtoV2() = %v", got)
+	}
+	if len(got.GetSummary().GetQuantiles()) != len(model.DefaultQuantiles) {
+		t.Errorf("quantiles = %v, want %d values", got.GetSummary().GetQuantiles(), len(model.DefaultQuantiles))
+	}
+}
+
+func TestFromProtoV2_Summary(t *testing.T) {
+	summary := model.NewSummary().Observe(1).Observe(2).Observe(3)
+	valid := toProtoV2(&model.Metric{ID: "latency", MType: model.SummaryType, Summary: summary})
+
+	tests := []struct {
+		name string
+		in   *pbv2.Metric
+		code codes.Code
+	}{
+		{
+			name: "Test 1",
+			in:   valid,
+			code: codes.OK,
+		},
+		{
+			name: "Test 2",
+			in: &pbv2.Metric{Id: "latency", Mtype: pbv2.Metric_SUMMARY, Payload: &pbv2.Metric_Summary{
+				Summary: &pbv2.Summary{Sum: 6, Count: 3},
+			}},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "Test 3",
+			in: &pbv2.Metric{Id: "latency", Mtype: pbv2.Metric_SUMMARY, Payload: &pbv2.Metric_Summary{
+				Summary: &pbv2.Summary{Count: 0, Sketch: &pbv2.Sketch{
+					Alpha:    0.01,
+					MaxBins:  16,
+					Positive: map[int32]int64{1: 5, 2: -5},
+				}},
+			}},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "Test 4",
+			in:   &pbv2.Metric{Id: "latency", Mtype: pbv2.Metric_GAUGE, Payload: valid.GetPayload()},
+			code: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fromProtoV2([]*pbv2.Metric{tt.in})
+			if status.Code(err) != tt.code {
+				t.Fatalf("fromProtoV2() code = %v, want %v", status.Code(err), tt.code)
+			}
+			if err != nil {
+				return
+			}
+
+			back := toProtoV2(&got[0])
+			if back.GetSummary().GetCount() != 3 || back.GetSummary().GetSum() != 6 ||
+				len(back.GetSummary().GetSketch().GetPositive()) != len(summary.Sketch.Positive) {
+				t.Errorf("round trip = %v, want %v", back, tt.in)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"metrix/internal/controllers"
+	"metrix/internal/model"
+	"metrix/internal/repository"
+	"metrix/pkg/logger"
+)
+
+// AgentsHandlers - the implementation structure for the AgentsHandlers that manages
+// access to the related controller.
+type AgentsHandlers struct {
+	controller controllers.AgentController
+}
+
+// NewAgentsHandlers - the builder function for the AgentsHandlers.
+func NewAgentsHandlers(repoGroup *repository.Group) *AgentsHandlers {
+	return &AgentsHandlers{
+		controller: controllers.NewAgentController(repoGroup),
+	}
+}
+
+// GetAll - the handler method that lists known agents with their last seen time.
+func (h *AgentsHandlers) GetAll(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ctx := r.Context()
+
+	agents, err := h.controller.GetAll(ctx)
+	if err != nil {
+		logger.Warn(ctx, fmt.Sprintf("failed to trigger controller: %v", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if agents == nil {
+		agents = &[]model.Agent{}
+	}
+
+	err = json.NewEncoder(w).Encode(agents)
+	if err != nil {
+		logger.Error(
+			ctx,
+			"failed to encode response json",
+			err,
+			"address", r.RemoteAddr,
+			"method", r.Method,
+			"url", r.URL,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"metrix/internal/controllers"
+	"metrix/internal/identity"
+	"metrix/internal/middlewares"
+	"metrix/internal/model"
+	"metrix/internal/repository"
+	"metrix/internal/validators"
+)
+
+func TestAgentsHandlers_GetAll(t *testing.T) {
+	ctx := context.Background()
+	repoGroup := repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1)
+	metrics := &MetricsHandlers{
+		controller: controllers.NewMetricController(repoGroup),
+		validator:  validators.NewMetricsValidator(),
+	}
+	setMany := middlewares.AgentMiddleware(http.HandlerFunc(metrics.SetMany))
+
+	tests := []struct {
+		name           string
+		agentID        string
+		wantStatusCode int
+	}{
+		{name: "Test 1: Report from identified agent", agentID: "host-42", wantStatusCode: 200},
+		{name: "Test 2: Report from anonymous agent", agentID: "", wantStatusCode: 200},
+		{name: "Test 3: Report with malformed agent id", agentID: "bad id", wantStatusCode: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte(`[{"id":"PollCount","type":"counter","delta":1}]`)
+			r := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBuffer(body))
+			r.Header.Set("Content-Type", "application/json")
+			if tt.agentID != "" {
+				r.Header.Set(identity.AgentIDHeader, tt.agentID)
+				r.Header.Set(identity.ReportIntervalHeader, "15")
+			}
+			w := httptest.NewRecorder()
+			setMany.ServeHTTP(w, r)
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("status codes are different: got=%d want=%d", w.Code, tt.wantStatusCode)
+			}
+		})
+	}
+
+	h := NewAgentsHandlers(repoGroup)
+	w := httptest.NewRecorder()
+	h.GetAll(w, httptest.NewRequest(http.MethodGet, "/api/v1/agents", http.NoBody))
+	if w.Code != http.StatusOK {
+		t.Errorf("status codes are different: got=%d want=%d", w.Code, http.StatusOK)
+		return
+	}
+
+	agents := []model.Agent{}
+	if err := json.NewDecoder(w.Body).Decode(&agents); err != nil {
+		t.Errorf("failed to decode agents: %v", err)
+		return
+	}
+	want := []model.Agent{{ID: "host-42", ReportInterval: 15, Status: model.HealthyAgentStatus}}
+	for i := range agents {
+		agents[i].LastSeen = time.Time{}
+	}
+	if !reflect.DeepEqual(agents, want) {
+		t.Errorf("AgentsHandlers.GetAll() = %+v, want %+v", agents, want)
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"metrix/internal/alerting"
+	"metrix/pkg/logger"
+)
+
+// AlertsHandlers - the implementation structure for the AlertsHandlers that manages
+// access to the alerting engine.
+type AlertsHandlers struct {
+	engine *alerting.Engine
+}
+
+// NewAlertsHandlers - the builder function for the AlertsHandlers.
+func NewAlertsHandlers(engine *alerting.Engine) *AlertsHandlers {
+	return &AlertsHandlers{engine: engine}
+}
+
+// GetAll - the handler method that lists pending and firing alerts.
+func (h *AlertsHandlers) GetAll(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ctx := r.Context()
+
+	alerts := []alerting.Alert{}
+	if h.engine != nil {
+		alerts = h.engine.Alerts()
+	}
+
+	err := json.NewEncoder(w).Encode(alerts)
+	if err != nil {
+		logger.Error(
+			ctx,
+			"failed to encode response json",
+			err,
+			"address", r.RemoteAddr,
+			"method", r.Method,
+			"url", r.URL,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
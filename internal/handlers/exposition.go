@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"metrix/internal/model"
+)
+
+// expositionContentType - the content type of Prometheus text exposition format.
+const expositionContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// sanitizeMetricName - the function that maps metric ID to a valid Prometheus metric name,
+// every character outside of [a-zA-Z0-9_:] is replaced by underscore.
+func sanitizeMetricName(id string) string {
+	var b strings.Builder
+	for i, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+
+	if b.Len() == 0 {
+		return "_"
+	}
+
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// labelValueReplacer - the replacer escaping backslash, double-quote and line feed in label values.
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels - the function that renders labels with extra pairs appended in Prometheus
+// text format, e.g. {host="h42",le="0.5"}. Label values are escaped as the format requires.
+func formatLabels(labels model.Labels, extra ...string) string {
+	if len(labels) == 0 && len(extra) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names)+len(extra)/2)
+	for _, k := range names {
+		pairs = append(pairs, k+"=\""+labelValueReplacer.Replace(labels[k])+"\"")
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+"=\""+labelValueReplacer.Replace(extra[i+1])+"\"")
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// writeExposition - the function that renders metrics in Prometheus text format.
+// Series are grouped by name and sorted by labels, the TYPE line is written once per name.
+// Series mapped to a name already rendered with another type or the same labels are skipped.
+func writeExposition(w io.Writer, metrics []model.Metric) error {
+	sort.SliceStable(metrics, func(i, j int) bool {
+		ni, nj := sanitizeMetricName(metrics[i].ID), sanitizeMetricName(metrics[j].ID)
+		if ni != nj {
+			return ni < nj
+		}
+		return metrics[i].Labels.String() < metrics[j].Labels.String()
+	})
+
+	var b strings.Builder
+	types := map[string]model.MType{}
+	rendered := map[string]bool{}
+	for i := range metrics {
+		m := &metrics[i]
+		name := sanitizeMetricName(m.ID)
+		series := name + formatLabels(m.Labels)
+		if rendered[series] {
+			continue
+		}
+		if mtype, ok := types[name]; ok && mtype != m.MType {
+			continue
+		}
+
+		var body strings.Builder
+		switch m.MType {
+		case model.GaugeType:
+			if m.Value == nil {
+				continue
+			}
+			fmt.Fprintf(&body, "%s %s\n", series, formatFloat(*m.Value))
+		case model.CounterType:
+			if m.Delta == nil {
+				continue
+			}
+			fmt.Fprintf(&body, "%s %d\n", series, *m.Delta)
+		case model.HistogramType:
+			if m.Histogram == nil {
+				continue
+			}
+			writeHistogram(&body, name, m.Labels, m.Histogram)
+		case model.SummaryType:
+			if m.Summary == nil || m.Summary.Sketch == nil {
+				continue
+			}
+			writeSummary(&body, name, m.Labels, m.Summary)
+		default:
+			continue
+		}
+
+		if _, ok := types[name]; !ok {
+			fmt.Fprintf(&b, "# TYPE %s %s\n", name, m.MType)
+			types[name] = m.MType
+		}
+		b.WriteString(body.String())
+		rendered[series] = true
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write exposition: %w", err)
+	}
+
+	return nil
+}
+
+func writeHistogram(b *strings.Builder, name string, labels model.Labels, h *model.Histogram) {
+	var cumulative int64
+	for i, bound := range h.Bounds {
+		cumulative += h.Counts[i]
+		fmt.Fprintf(b, "%s_bucket%s %d\n", name, formatLabels(labels, "le", formatFloat(bound)), cumulative)
+	}
+	fmt.Fprintf(b, "%s_bucket%s %d\n", name, formatLabels(labels, "le", "+Inf"), h.Count)
+	fmt.Fprintf(b, "%s_sum%s %s\n", name, formatLabels(labels), formatFloat(h.Sum))
+	fmt.Fprintf(b, "%s_count%s %d\n", name, formatLabels(labels), h.Count)
+}
+
+func writeSummary(b *strings.Builder, name string, labels model.Labels, s *model.Summary) {
+	if s.Sketch.Count > 0 {
+		for _, q := range model.DefaultQuantiles {
+			v, err := s.Sketch.Quantile(q)
+			if err != nil {
+				continue
+			}
+			quantile := formatLabels(labels, "quantile", model.FormatQuantile(q))
+			fmt.Fprintf(b, "%s%s %s\n", name, quantile, formatFloat(v))
+		}
+	}
+	fmt.Fprintf(b, "%s_sum%s %s\n", name, formatLabels(labels), formatFloat(s.Sketch.Sum))
+	fmt.Fprintf(b, "%s_count%s %d\n", name, formatLabels(labels), s.Sketch.Count)
+}
@@ -22,6 +22,12 @@ func NewHealthHandlers(repoGroup *repository.Group) *HealthHandlers {
 	}
 }
 
+// Controller - the method that returns the health controller, so other transports
+// report the same state.
+func (h *HealthHandlers) Controller() controllers.HealthController {
+	return h.controller
+}
+
 // SetReadiness - the method that sets "rediness" status for the service via handler.
 func (h *HealthHandlers) SetReadiness(state bool) {
 	h.controller.SetReadiness(state)
@@ -10,7 +10,7 @@ import (
 
 func TestHealthHandlers_SetReadiness(t *testing.T) {
 	ctx := context.Background()
-	repoGroup := repository.NewGroup(ctx, nil, "", 0, false)
+	repoGroup := repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1)
 	controller := controllers.NewHealthController(repoGroup)
 
 	type fields struct {
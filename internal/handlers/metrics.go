@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"metrix/internal/controllers"
+	"metrix/internal/model"
 	"metrix/internal/repository"
 	"metrix/internal/validators"
 	"metrix/pkg/logger"
@@ -52,6 +53,12 @@ func (h *MetricsHandlers) Set(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	metricIn.Labels, err = h.validator.LabelsFromQuery(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
 	metric, err := h.controller.Set(ctx, metricIn)
 	if err != nil {
 		logger.Warn(ctx, fmt.Sprintf("failed to trigger controller: %v", err))
@@ -163,7 +170,19 @@ func (h *MetricsHandlers) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	metric, err := h.controller.Get(ctx, metricID)
+	quantiles, err := model.ParseQuantiles(r.URL.Query().Get("q"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	labels, err := h.validator.LabelsFromQuery(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	metric, err := h.controller.Get(ctx, metricID, labels)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -174,6 +193,12 @@ func (h *MetricsHandlers) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := withQuantiles(metric, quantiles); err != nil {
+		logger.Warn(ctx, fmt.Sprintf("failed to estimate quantiles: %v", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	_, err = w.Write([]byte(metric.GetValue()))
 	if err != nil {
 		logger.Error(ctx, "failed to trigger controller", err)
@@ -200,7 +225,7 @@ func (h *MetricsHandlers) GetWithModel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	metric, err := h.controller.Get(ctx, metricIn.ID)
+	metric, err := h.controller.Get(ctx, metricIn.ID, metricIn.Labels)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -211,6 +236,12 @@ func (h *MetricsHandlers) GetWithModel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := withQuantiles(metric, metricIn.Quantiles); err != nil {
+		logger.Warn(ctx, fmt.Sprintf("failed to estimate quantiles: %v", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	err = json.NewEncoder(w).Encode(metric)
 	if err != nil {
 		logger.Error(
@@ -227,13 +258,20 @@ func (h *MetricsHandlers) GetWithModel(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// GetIDs - the handler method that incapsulates validation logic for getting metrics ids.
+// GetIDs - the handler method that incapsulates validation logic for getting metrics ids,
+// the result may be filtered by labels passed as query parameters.
 func (h *MetricsHandlers) GetIDs(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html")
 
 	ctx := r.Context()
 
-	ids, err := h.controller.GetIDs(ctx)
+	selector, err := h.validator.LabelsFromQuery(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ids, err := h.controller.GetIDs(ctx, selector)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -250,3 +288,89 @@ func (h *MetricsHandlers) GetIDs(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
+
+// Exposition - the handler method that renders all stored metrics in Prometheus text format,
+// the result may be filtered by labels passed as query parameters.
+func (h *MetricsHandlers) Exposition(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", expositionContentType)
+
+	ctx := r.Context()
+
+	selector, err := h.validator.LabelsFromQuery(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	metrics, err := h.controller.GetAll(ctx, selector)
+	if err != nil {
+		logger.Warn(ctx, fmt.Sprintf("failed to trigger controller: %v", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if metrics == nil {
+		metrics = &[]model.Metric{}
+	}
+
+	if err := writeExposition(w, *metrics); err != nil {
+		logger.Error(ctx, "failed to write exposition", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// GetRange - the handler method that incapsulates validation logic for getting metric
+// history within a time range with server-side downsampling.
+func (h *MetricsHandlers) GetRange(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ctx := r.Context()
+
+	query, err := h.validator.RangeFromQuery(r.URL.Query())
+	if err != nil {
+		var parsingValueError validators.ParsingValueError
+		if errors.As(err, &parsingValueError) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Warn(ctx, fmt.Sprintf(parseErrMsg, err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.controller.GetRange(ctx, query)
+	if err != nil {
+		logger.Warn(ctx, fmt.Sprintf("failed to trigger controller: %v", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		logger.Error(
+			ctx,
+			"failed to encode response json",
+			err,
+			"address", r.RemoteAddr,
+			"method", r.Method,
+			"url", r.URL,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// withQuantiles - the function that estimates requested quantiles for summary metrics.
+func withQuantiles(metric *model.Metric, quantiles []float64) error {
+	if metric.MType != model.SummaryType || metric.Summary == nil {
+		return nil
+	}
+
+	summary, err := metric.Summary.WithQuantiles(quantiles)
+	if err != nil {
+		return fmt.Errorf("failed to estimate quantiles: %w", err)
+	}
+	metric.Summary = summary
+
+	return nil
+}
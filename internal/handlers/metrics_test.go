@@ -3,19 +3,27 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"math"
 	"metrix/internal/controllers"
+	"metrix/internal/model"
 	"metrix/internal/repository"
 	"metrix/internal/validators"
+	"metrix/pkg/sketch"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
 func TestMetricsHandlers_Set(t *testing.T) {
 	ctx := context.Background()
-	repoGroup := repository.NewGroup(ctx, nil, "", 0, false)
+	repoGroup := repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1)
 	controller := controllers.NewMetricController(repoGroup)
 	validator := validators.NewMetricsValidator()
 
@@ -86,7 +94,7 @@ func TestMetricsHandlers_Set(t *testing.T) {
 
 func TestMetricsHandlers_SetWithModel(t *testing.T) {
 	ctx := context.Background()
-	repoGroup := repository.NewGroup(ctx, nil, "", 0, false)
+	repoGroup := repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1)
 	controller := controllers.NewMetricController(repoGroup)
 	validator := validators.NewMetricsValidator()
 
@@ -124,6 +132,52 @@ func TestMetricsHandlers_SetWithModel(t *testing.T) {
 				wantStatusCode: 200,
 			},
 		},
+		{
+			name: "Test 2: Set histogram metric",
+			fields: fields{
+				controller: controller,
+				validator:  validator,
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest(
+					http.MethodPost,
+					"/update/",
+					bytes.NewBuffer(
+						func() []byte {
+							return []byte(
+								`{"id": "latency", "type": "histogram", ` +
+									`"histogram": {"bounds": [0.1, 1], "counts": [1, 0, 0], "sum": 0.05, "count": 1}}`,
+							)
+						}(),
+					),
+				),
+				wantStatusCode: 200,
+			},
+		},
+		{
+			name: "Test 3: Set inconsistent histogram metric",
+			fields: fields{
+				controller: controller,
+				validator:  validator,
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest(
+					http.MethodPost,
+					"/update/",
+					bytes.NewBuffer(
+						func() []byte {
+							return []byte(
+								`{"id": "latency", "type": "histogram", ` +
+									`"histogram": {"bounds": [0.1, 1], "counts": [1, 0], "sum": 0.05, "count": 1}}`,
+							)
+						}(),
+					),
+				),
+				wantStatusCode: 400,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -149,7 +203,7 @@ func TestMetricsHandlers_SetWithModel(t *testing.T) {
 
 func TestMetricsHandlers_SetMany(t *testing.T) {
 	ctx := context.Background()
-	repoGroup := repository.NewGroup(ctx, nil, "", 0, false)
+	repoGroup := repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1)
 	controller := controllers.NewMetricController(repoGroup)
 	validator := validators.NewMetricsValidator()
 
@@ -209,3 +263,390 @@ func TestMetricsHandlers_SetMany(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricsHandlers_GetWithModel(t *testing.T) {
+	ctx := context.Background()
+	repoGroup := repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1)
+	controller := controllers.NewMetricController(repoGroup)
+	validator := validators.NewMetricsValidator()
+
+	agentA := model.NewSummary()
+	agentB := model.NewSummary()
+	for i := 1; i <= 100; i++ {
+		if i <= 50 {
+			agentA = agentA.Observe(float64(i))
+		} else {
+			agentB = agentB.Observe(float64(i))
+		}
+	}
+
+	_, err := controller.SetMany(ctx, []*model.Metric{
+		{ID: "latency", MType: model.SummaryType, Summary: agentA},
+		{ID: "latency", MType: model.SummaryType, Summary: agentB},
+	})
+	if err != nil {
+		t.Errorf("MetricsHandlers.GetWithModel() error = %v", err)
+		return
+	}
+
+	type fields struct {
+		controller controllers.MetricsController
+		validator  validators.MetricsValidator
+	}
+	type args struct {
+		w              http.ResponseWriter
+		r              *http.Request
+		wantStatusCode int
+		wantQuantiles  map[string]float64
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+	}{
+		{
+			name: "Test 1: Get summary quantiles",
+			fields: fields{
+				controller: controller,
+				validator:  validator,
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest(
+					http.MethodPost,
+					"/value/",
+					bytes.NewBuffer(
+						func() []byte {
+							return []byte(`{"id": "latency", "type": "summary", "quantiles": [0.5, 0.99]}`)
+						}(),
+					),
+				),
+				wantStatusCode: 200,
+				wantQuantiles:  map[string]float64{"0.5": 50, "0.99": 99},
+			},
+		},
+		{
+			name: "Test 2: Get summary with wrong quantile",
+			fields: fields{
+				controller: controller,
+				validator:  validator,
+			},
+			args: args{
+				w: httptest.NewRecorder(),
+				r: httptest.NewRequest(
+					http.MethodPost,
+					"/value/",
+					bytes.NewBuffer(
+						func() []byte {
+							return []byte(`{"id": "latency", "type": "summary", "quantiles": [1.5]}`)
+						}(),
+					),
+				),
+				wantStatusCode: 400,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &MetricsHandlers{
+				controller: tt.fields.controller,
+				validator:  tt.fields.validator,
+			}
+			h.GetWithModel(tt.args.w, tt.args.r)
+			r, ok := tt.args.w.(*httptest.ResponseRecorder)
+			if !ok {
+				t.Errorf("got different from *httptest.ResponseRecorder struct: %+v", r)
+				return
+			}
+			if r.Code != tt.args.wantStatusCode {
+				t.Errorf(
+					"status codes are different: got=%d want=%d",
+					r.Code,
+					tt.args.wantStatusCode,
+				)
+				return
+			}
+			if tt.args.wantQuantiles == nil {
+				return
+			}
+
+			metric := model.Metric{}
+			if err := json.NewDecoder(r.Body).Decode(&metric); err != nil {
+				t.Errorf("failed to decode response: %v", err)
+				return
+			}
+			for q, want := range tt.args.wantQuantiles {
+				got := metric.Summary.Quantiles[q]
+				if math.Abs(got-want) > want*sketch.DefaultRelativeAccuracy {
+					t.Errorf("quantile %s = %v, want %v", q, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsHandlers_GetRange(t *testing.T) {
+	ctx := context.Background()
+	repoGroup := repository.NewGroup(ctx, nil, nil, "", 0, false, time.Hour, 1)
+	controller := controllers.NewMetricController(repoGroup)
+	validator := validators.NewMetricsValidator()
+
+	for _, v := range []float64{10, 20} {
+		value := v
+		_, err := controller.Set(ctx, &model.Metric{ID: "HeapAlloc", MType: model.GaugeType, Value: &value})
+		if err != nil {
+			t.Errorf("MetricsHandlers.GetRange() error = %v", err)
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	query := url.Values{
+		"id":   []string{"HeapAlloc"},
+		"from": []string{now.Add(-time.Minute).Format(time.RFC3339)},
+		"to":   []string{strconv.FormatInt(now.Add(time.Minute).Unix(), 10)},
+		"step": []string{"5m"},
+	}
+
+	type fields struct {
+		controller controllers.MetricsController
+		validator  validators.MetricsValidator
+	}
+	type args struct {
+		w              http.ResponseWriter
+		r              *http.Request
+		wantStatusCode int
+		wantPoints     []float64
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+	}{
+		{
+			name: "Test 1: Get averaged history",
+			fields: fields{
+				controller: controller,
+				validator:  validator,
+			},
+			args: args{
+				w:              httptest.NewRecorder(),
+				r:              httptest.NewRequest(http.MethodGet, "/api/v1/query_range?"+query.Encode(), http.NoBody),
+				wantStatusCode: 200,
+				wantPoints:     []float64{15},
+			},
+		},
+		{
+			name: "Test 2: Get history without id",
+			fields: fields{
+				controller: controller,
+				validator:  validator,
+			},
+			args: args{
+				w:              httptest.NewRecorder(),
+				r:              httptest.NewRequest(http.MethodGet, "/api/v1/query_range?step=1m", http.NoBody),
+				wantStatusCode: 400,
+			},
+		},
+		{
+			name: "Test 3: Get history with unknown aggregation",
+			fields: fields{
+				controller: controller,
+				validator:  validator,
+			},
+			args: args{
+				w:              httptest.NewRecorder(),
+				r:              httptest.NewRequest(http.MethodGet, "/api/v1/query_range?id=HeapAlloc&agg=p99", http.NoBody),
+				wantStatusCode: 400,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &MetricsHandlers{
+				controller: tt.fields.controller,
+				validator:  tt.fields.validator,
+			}
+			h.GetRange(tt.args.w, tt.args.r)
+			r, ok := tt.args.w.(*httptest.ResponseRecorder)
+			if !ok {
+				t.Errorf("got different from *httptest.ResponseRecorder struct: %+v", r)
+				return
+			}
+			if r.Code != tt.args.wantStatusCode {
+				t.Errorf(
+					"status codes are different: got=%d want=%d",
+					r.Code,
+					tt.args.wantStatusCode,
+				)
+				return
+			}
+			if tt.args.wantPoints == nil {
+				return
+			}
+
+			result := model.RangeResult{}
+			if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
+				t.Errorf("failed to decode response: %v", err)
+				return
+			}
+			got := []float64{}
+			for _, p := range result.Points {
+				got = append(got, p.Value)
+			}
+			if !reflect.DeepEqual(got, tt.args.wantPoints) {
+				t.Errorf("points = %v, want %v", got, tt.args.wantPoints)
+			}
+		})
+	}
+}
+
+func TestMetricsHandlers_Exposition(t *testing.T) {
+	ctx := context.Background()
+	repoGroup := repository.NewGroup(ctx, nil, nil, "", 0, false, 0, 1)
+	controller := controllers.NewMetricController(repoGroup)
+	validator := validators.NewMetricsValidator()
+
+	_, err := controller.SetMany(ctx, []*model.Metric{
+		{ID: "PollCount", MType: model.CounterType, Delta: func() *int64 { i := int64(5); return &i }()},
+		{
+			ID:     "PollCount",
+			MType:  model.CounterType,
+			Labels: model.Labels{"host": "h1"},
+			Delta:  func() *int64 { i := int64(2); return &i }(),
+		},
+		{
+			ID:     "PollCount",
+			MType:  model.CounterType,
+			Labels: model.Labels{"host": "h\"2"},
+			Delta:  func() *int64 { i := int64(3); return &i }(),
+		},
+		{ID: "Heap.Alloc", MType: model.GaugeType, Value: func() *float64 { i := float64(1.5); return &i }()},
+		{
+			ID:    "latency",
+			MType: model.HistogramType,
+			Histogram: &model.Histogram{
+				Bounds: []float64{0.1, 1},
+				Counts: []int64{1, 2, 1},
+				Sum:    3.25,
+				Count:  4,
+			},
+		},
+	})
+	if err != nil {
+		t.Errorf("MetricsHandlers.Exposition() error = %v", err)
+		return
+	}
+
+	type fields struct {
+		controller controllers.MetricsController
+		validator  validators.MetricsValidator
+	}
+	type args struct {
+		w              http.ResponseWriter
+		r              *http.Request
+		wantStatusCode int
+		wantBody       string
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+	}{
+		{
+			name: "Test 1: Render metrics",
+			fields: fields{
+				controller: controller,
+				validator:  validator,
+			},
+			args: args{
+				w:              httptest.NewRecorder(),
+				r:              httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody),
+				wantStatusCode: 200,
+				wantBody: "# TYPE Heap_Alloc gauge\n" +
+					"Heap_Alloc 1.5\n" +
+					"# TYPE PollCount counter\n" +
+					"PollCount 5\n" +
+					"PollCount{host=\"h1\"} 2\n" +
+					"PollCount{host=\"h\\\"2\"} 3\n" +
+					"# TYPE latency histogram\n" +
+					"latency_bucket{le=\"0.1\"} 1\n" +
+					"latency_bucket{le=\"1\"} 3\n" +
+					"latency_bucket{le=\"+Inf\"} 4\n" +
+					"latency_sum 3.25\n" +
+					"latency_count 4\n",
+			},
+		},
+		{
+			name: "Test 2: Render metrics filtered by label",
+			fields: fields{
+				controller: controller,
+				validator:  validator,
+			},
+			args: args{
+				w:              httptest.NewRecorder(),
+				r:              httptest.NewRequest(http.MethodGet, "/metrics?label=host:h1", http.NoBody),
+				wantStatusCode: 200,
+				wantBody: "# TYPE PollCount counter\n" +
+					"PollCount{host=\"h1\"} 2\n",
+			},
+		},
+		{
+			name: "Test 3: Malformed label filter",
+			fields: fields{
+				controller: controller,
+				validator:  validator,
+			},
+			args: args{
+				w:              httptest.NewRecorder(),
+				r:              httptest.NewRequest(http.MethodGet, "/metrics?label=host", http.NoBody),
+				wantStatusCode: 400,
+				wantBody:       "",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &MetricsHandlers{
+				controller: tt.fields.controller,
+				validator:  tt.fields.validator,
+			}
+			h.Exposition(tt.args.w, tt.args.r)
+			r, ok := tt.args.w.(*httptest.ResponseRecorder)
+			if !ok {
+				t.Errorf("got different from *httptest.ResponseRecorder struct: %+v", r)
+				return
+			}
+			if r.Code != tt.args.wantStatusCode {
+				t.Errorf(
+					"status codes are different: got=%d want=%d",
+					r.Code,
+					tt.args.wantStatusCode,
+				)
+			}
+			if r.Body.String() != tt.args.wantBody {
+				t.Errorf("body = %q, want %q", r.Body.String(), tt.args.wantBody)
+			}
+		})
+	}
+}
+
+func Test_sanitizeMetricName(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want string
+	}{
+		{name: "Test 1: Valid name", id: "HeapAlloc", want: "HeapAlloc"},
+		{name: "Test 2: Invalid characters", id: "cpu.utilization-1", want: "cpu_utilization_1"},
+		{name: "Test 3: Leading digit", id: "1xx", want: "_1xx"},
+		{name: "Test 4: Empty", id: "", want: "_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeMetricName(tt.id); got != tt.want {
+				t.Errorf("sanitizeMetricName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"metrix/internal/config"
+	"metrix/internal/grpcapi/grpcservice"
+	pbv2 "metrix/internal/grpcapi/proto/v2"
+	"metrix/internal/handlers"
+	"metrix/internal/identity"
+	"metrix/internal/model"
+	"metrix/internal/repository"
+	"metrix/internal/storages"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// newGatewayTestServer - the function that builds http handler with the REST gateway
+// and the gRPC service on top of a fresh in-memory repository.
+func newGatewayTestServer(
+	t *testing.T,
+) (http.Handler, *grpcservice.GServiceServer, repository.MetricRepository) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	repoGroup := &repository.Group{
+		MetricRepo: storages.NewInMemoryStorage(ctx, "", 0, false, 0, 1),
+		AgentRepo:  storages.NewAgentMemoryStorage(),
+	}
+
+	gs := grpcservice.NewGServiceServer(repoGroup.MetricRepo, repoGroup.AgentRepo, nil, nil)
+	gateway, err := gs.Gateway(ctx)
+	if err != nil {
+		t.Fatalf("Gateway() error = %v", err)
+	}
+
+	s := New(
+		&config.Config{},
+		handlers.NewHealthHandlers(repoGroup),
+		handlers.NewMetricsHandlers(repoGroup),
+		handlers.NewAgentsHandlers(repoGroup),
+		nil,
+		gateway,
+	)
+
+	return s.setupRoutes(), gs, repoGroup.MetricRepo
+}
+
+// storedMetrics - the function that reads every stored metric ordered by series key.
+func storedMetrics(t *testing.T, repo repository.MetricRepository) []model.Metric {
+	t.Helper()
+
+	ctx := context.Background()
+	ids, err := repo.ReadIDs(ctx)
+	if err != nil {
+		t.Fatalf("ReadIDs() error = %v", err)
+	}
+
+	metrics, err := repo.ReadMany(ctx, *ids)
+	if err != nil {
+		t.Fatalf("ReadMany() error = %v", err)
+	}
+
+	sort.Slice(*metrics, func(i, j int) bool {
+		return (*metrics)[i].SeriesKey() < (*metrics)[j].SeriesKey()
+	})
+
+	return *metrics
+}
+
+func post(t *testing.T, handler http.Handler, url string, body []byte) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(identity.AgentIDHeader, "agent-1")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST %s status = %d, body = %s", url, w.Code, w.Body.String())
+	}
+}
+
+func TestGateway_Parity(t *testing.T) {
+	delta := int64(1<<40 + 1)
+	value := 0.1 + 0.2
+
+	metrics := []model.Metric{
+		{ID: "PollCount", MType: model.CounterType, Delta: &delta},
+		{ID: "PollCount", MType: model.CounterType, Delta: &delta},
+		{ID: "Alloc", MType: model.GaugeType, Value: &value, Labels: model.Labels{"dc": "eu"}},
+		{ID: "Latency", MType: model.HistogramType, Histogram: &model.Histogram{
+			Bounds: []float64{0.1, 1},
+			Counts: []int64{1, 2, 0},
+			Sum:    1.5,
+			Count:  3,
+		}},
+	}
+
+	legacy, err := json.Marshal(metrics)
+	if err != nil {
+		t.Fatalf("failed to marshal metrics: %s", err)
+	}
+
+	request := &pbv2.MetricsRequest{Items: []*pbv2.Metric{
+		{Id: "PollCount", Mtype: pbv2.Metric_COUNTER, Payload: &pbv2.Metric_Delta{Delta: delta}},
+		{Id: "PollCount", Mtype: pbv2.Metric_COUNTER, Payload: &pbv2.Metric_Delta{Delta: delta}},
+		{
+			Id:      "Alloc",
+			Mtype:   pbv2.Metric_GAUGE,
+			Payload: &pbv2.Metric_Value{Value: value},
+			Labels:  map[string]string{"dc": "eu"},
+		},
+		{Id: "Latency", Mtype: pbv2.Metric_HISTOGRAM, Payload: &pbv2.Metric_Histogram{
+			Histogram: &pbv2.Histogram{Bounds: []float64{0.1, 1}, Counts: []int64{1, 2, 0}, Sum: 1.5, Count: 3},
+		}},
+	}}
+	gateway, err := protojson.Marshal(request)
+	if err != nil {
+		t.Fatalf("failed to marshal request: %s", err)
+	}
+
+	legacyHandler, _, legacyRepo := newGatewayTestServer(t)
+	post(t, legacyHandler, "/updates/", legacy)
+
+	_, gs, grpcRepo := newGatewayTestServer(t)
+	ctx := metadata.NewIncomingContext(
+		context.Background(),
+		metadata.Pairs(identity.AgentIDMetadataKey, "agent-1"),
+	)
+	if _, err := gs.V2().SetMetrics(ctx, request); err != nil {
+		t.Fatalf("SetMetrics() error = %v", err)
+	}
+
+	gatewayHandler, _, gatewayRepo := newGatewayTestServer(t)
+	post(t, gatewayHandler, "/api/v2/metrics", gateway)
+
+	want := storedMetrics(t, legacyRepo)
+	if len(want) != 3 {
+		t.Fatalf("legacy API stored %d series, want 3", len(want))
+	}
+	if *want[2].Delta != 2*delta {
+		t.Errorf("PollCount delta = %d, want %d", *want[2].Delta, 2*delta)
+	}
+
+	tests := []struct {
+		name string
+		repo repository.MetricRepository
+	}{
+		{name: "Test 1", repo: grpcRepo},
+		{name: "Test 2", repo: gatewayRepo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := storedMetrics(t, tt.repo)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("stored metrics = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestGateway_GetMetric(t *testing.T) {
+	handler, _, repo := newGatewayTestServer(t)
+
+	value := 42.5
+	_, err := repo.UpsertMany(context.Background(), []model.Metric{
+		{ID: "Alloc", MType: model.GaugeType, Value: &value, Labels: model.Labels{"dc": "eu"}},
+	})
+	if err != nil {
+		t.Fatalf("UpsertMany() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{name: "Test 1", url: "/api/v2/metrics/Alloc?labels[dc]=eu", wantStatus: http.StatusOK},
+		{name: "Test 2", url: "/api/v2/metrics/Alloc", wantStatus: http.StatusNotFound},
+		{name: "Test 3", url: "/api/v2/metrics?prefix=All", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("GET %s status = %d, want %d, body = %s", tt.url, w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Code == http.StatusOK && !bytes.Contains(w.Body.Bytes(), []byte(`"value":42.5`)) {
+				t.Errorf("GET %s body = %s, want gauge value", tt.url, w.Body.String())
+			}
+		})
+	}
+}
@@ -18,6 +18,8 @@ func (s *Server) setupRoutes() *mux.Router {
 
 	// Metrics handlers
 	m.HandleFunc("/", s.metrics.GetIDs)
+	m.HandleFunc("/metrics", s.metrics.Exposition).
+		Methods(http.MethodGet)
 
 	m.HandleFunc("/update/{type}/{id}/{value}", s.metrics.Set)
 	m.HandleFunc("/value/{type}/{id}", s.metrics.Get)
@@ -32,11 +34,28 @@ func (s *Server) setupRoutes() *mux.Router {
 		Methods(http.MethodPost).
 		Headers("Content-Type", "application/json")
 
+	m.HandleFunc("/api/v1/query_range", s.metrics.GetRange).
+		Methods(http.MethodGet)
+
+	// Agents handlers
+	m.HandleFunc("/api/v1/agents", s.agents.GetAll).
+		Methods(http.MethodGet)
+
+	// Alerts handlers
+	m.HandleFunc("/api/v1/alerts", s.alerts.GetAll).
+		Methods(http.MethodGet)
+
+	// REST gateway generated from the gRPC v2 API
+	if s.gateway != nil {
+		m.PathPrefix("/api/v2/").Handler(s.gateway)
+	}
+
 	m.Use(middlewares.SubnetMiddleware)
 	m.Use(middlewares.LoggingMiddleware)
 	m.Use(middlewares.SignatureMiddleware)
 	m.Use(middlewares.DecryptionMiddleware)
 	m.Use(middlewares.GzipMiddleware)
+	m.Use(middlewares.AgentMiddleware)
 
 	// pprof
 	m.PathPrefix("/debug/pprof/").Handler(http.DefaultServeMux)
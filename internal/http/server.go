@@ -3,11 +3,13 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"net/http"
 
 	"metrix/internal/closer"
 	"metrix/internal/config"
 	"metrix/internal/handlers"
+	"metrix/pkg/crypto"
 	"metrix/pkg/logger"
 
 	"github.com/pkg/errors"
@@ -19,6 +21,9 @@ type Server struct {
 	srv     *http.Server
 	health  *handlers.HealthHandlers
 	metrics *handlers.MetricsHandlers
+	agents  *handlers.AgentsHandlers
+	alerts  *handlers.AlertsHandlers
+	gateway http.Handler
 }
 
 // New - the builder function for server entity.
@@ -26,6 +31,9 @@ func New(
 	cfg *config.Config,
 	healthHandlers *handlers.HealthHandlers,
 	metricsHandlers *handlers.MetricsHandlers,
+	agentsHandlers *handlers.AgentsHandlers,
+	alertsHandlers *handlers.AlertsHandlers,
+	gateway http.Handler,
 ) *Server {
 	srv := &http.Server{
 		Addr: cfg.HTTPAddress,
@@ -36,6 +44,9 @@ func New(
 		srv:     srv,
 		health:  healthHandlers,
 		metrics: metricsHandlers,
+		agents:  agentsHandlers,
+		alerts:  alertsHandlers,
+		gateway: gateway,
 	}
 }
 
@@ -43,9 +54,25 @@ func New(
 func (s *Server) Start(ctx context.Context) {
 	s.srv.Handler = s.setupRoutes()
 
+	if s.cfg.HTTPTLSCert != "" {
+		tlsConfig, err := s.tlsConfig()
+		if err != nil {
+			logger.Fatal(ctx, "failed to load http tls config", err)
+		}
+		s.srv.TLSConfig = tlsConfig
+	}
+
 	go func() {
-		logger.Info(ctx, "starting listening http srv at "+s.cfg.HTTPAddress)
-		if err := s.srv.ListenAndServe(); err != http.ErrServerClosed {
+		var err error
+		if s.srv.TLSConfig != nil {
+			logger.Info(ctx, "starting listening https srv at "+s.cfg.HTTPAddress)
+			err = s.srv.ListenAndServeTLS("", "")
+		} else {
+			logger.Info(ctx, "starting listening http srv at "+s.cfg.HTTPAddress)
+			err = s.srv.ListenAndServe()
+		}
+
+		if err != http.ErrServerClosed {
 			logger.Fatal(ctx, "error start http srv, err: %+v", err)
 		}
 	}()
@@ -53,6 +80,22 @@ func (s *Server) Start(ctx context.Context) {
 	closer.Add(s.Close)
 }
 
+// tlsConfig - the method that builds TLS config for https from the server config.
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	tlsConfig, err := crypto.ServerTLSConfig(s.cfg.HTTPTLSCert, s.cfg.HTTPTLSKey, s.cfg.HTTPClientCA)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build tls config")
+	}
+
+	minVersion, err := crypto.ParseTLSVersion(s.cfg.HTTPTLSMinVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse tls min version")
+	}
+	tlsConfig.MinVersion = minVersion
+
+	return tlsConfig, nil
+}
+
 // Close - the method to close the http-server.
 func (s *Server) Close() error {
 	ctx := context.TODO()
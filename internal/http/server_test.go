@@ -0,0 +1,89 @@
+package http
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"metrix/internal/config"
+	"metrix/pkg/crypto"
+)
+
+func TestServer_tlsConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	ca, err := crypto.NewCertificateAuthority("test ca", time.Hour)
+	if err != nil {
+		t.Fatalf("NewCertificateAuthority() error = %s", err)
+	}
+	certPEM, keyPEM, err := ca.IssueServer("localhost", []string{"localhost"}, time.Hour)
+	if err != nil {
+		t.Fatalf("IssueServer() error = %s", err)
+	}
+
+	files := map[string][]byte{"ca.crt": ca.CertPEM(), "server.crt": certPEM, "server.key": keyPEM}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatalf("failed to write %s: %s", name, err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		cfg            *config.Config
+		wantMinVersion uint16
+		wantClientAuth tls.ClientAuthType
+		wantErr        bool
+	}{
+		{
+			name: "Test 1",
+			cfg: &config.Config{
+				HTTPTLSCert:       filepath.Join(dir, "server.crt"),
+				HTTPTLSKey:        filepath.Join(dir, "server.key"),
+				HTTPTLSMinVersion: "1.3",
+			},
+			wantMinVersion: tls.VersionTLS13,
+			wantClientAuth: tls.NoClientCert,
+		},
+		{
+			name: "Test 2",
+			cfg: &config.Config{
+				HTTPTLSCert:       filepath.Join(dir, "server.crt"),
+				HTTPTLSKey:        filepath.Join(dir, "server.key"),
+				HTTPTLSMinVersion: "1.2",
+				HTTPClientCA:      filepath.Join(dir, "ca.crt"),
+			},
+			wantMinVersion: tls.VersionTLS12,
+			wantClientAuth: tls.RequireAndVerifyClientCert,
+		},
+		{
+			name: "Test 3",
+			cfg: &config.Config{
+				HTTPTLSCert:       filepath.Join(dir, "missing.crt"),
+				HTTPTLSKey:        filepath.Join(dir, "server.key"),
+				HTTPTLSMinVersion: "1.2",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{cfg: tt.cfg}
+			got, err := s.tlsConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("tlsConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.MinVersion != tt.wantMinVersion || got.ClientAuth != tt.wantClientAuth {
+				t.Errorf(
+					"tlsConfig() min version = %x client auth = %v, want %x %v",
+					got.MinVersion, got.ClientAuth, tt.wantMinVersion, tt.wantClientAuth,
+				)
+			}
+		})
+	}
+}
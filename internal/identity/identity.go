@@ -0,0 +1,79 @@
+// Module "identity" carries the identity of the reporting agent through request contexts.
+package identity
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// AgentIDHeader - the HTTP header an agent uses to introduce itself.
+const AgentIDHeader = "X-Agent-ID"
+
+// AgentIDMetadataKey - the gRPC metadata key an agent uses to introduce itself.
+const AgentIDMetadataKey = "x-agent-id"
+
+// ReportIntervalHeader - the HTTP header an agent uses to declare its report interval in seconds.
+const ReportIntervalHeader = "X-Agent-Report-Interval"
+
+// ReportIntervalMetadataKey - the gRPC metadata key an agent uses to declare its report
+// interval in seconds.
+const ReportIntervalMetadataKey = "x-agent-report-interval"
+
+var agentIDRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._:-]{0,127}$`)
+
+type agentIDKey struct{}
+
+type reportIntervalKey struct{}
+
+// ValidateAgentID - the function that checks agent ID format.
+func ValidateAgentID(agentID string) error {
+	if !agentIDRe.MatchString(agentID) {
+		return fmt.Errorf("invalid agent id %q", agentID)
+	}
+
+	return nil
+}
+
+// WithAgentID - the function that builds a context carrying the agent ID.
+func WithAgentID(ctx context.Context, agentID string) context.Context {
+	return context.WithValue(ctx, agentIDKey{}, agentID)
+}
+
+// AgentIDFromContext - the function that retrieves the agent ID from context.
+func AgentIDFromContext(ctx context.Context) (string, bool) {
+	agentID, ok := ctx.Value(agentIDKey{}).(string)
+	return agentID, ok && agentID != ""
+}
+
+// ParseReportInterval - the function that parses the declared report interval in seconds.
+func ParseReportInterval(raw string) (time.Duration, error) {
+	seconds, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse report interval: %w", err)
+	}
+
+	if seconds <= 0 {
+		return 0, fmt.Errorf("report interval must be positive, got %d", seconds)
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// FormatReportInterval - the function that renders the report interval in seconds.
+func FormatReportInterval(interval time.Duration) string {
+	return strconv.FormatInt(int64(interval/time.Second), 10)
+}
+
+// WithReportInterval - the function that builds a context carrying the declared report interval.
+func WithReportInterval(ctx context.Context, interval time.Duration) context.Context {
+	return context.WithValue(ctx, reportIntervalKey{}, interval)
+}
+
+// ReportIntervalFromContext - the function that retrieves the declared report interval from context.
+func ReportIntervalFromContext(ctx context.Context) (time.Duration, bool) {
+	interval, ok := ctx.Value(reportIntervalKey{}).(time.Duration)
+	return interval, ok && interval > 0
+}
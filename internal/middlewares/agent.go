@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"metrix/internal/identity"
+)
+
+// AgentMiddleware - the net/http middleware function that puts the agent ID and
+// its declared report interval from the request headers into the request context.
+func AgentMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agentID := r.Header.Get(identity.AgentIDHeader)
+		if agentID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err := identity.ValidateAgentID(agentID); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx := identity.WithAgentID(r.Context(), agentID)
+		if raw := r.Header.Get(identity.ReportIntervalHeader); raw != "" {
+			interval, err := identity.ParseReportInterval(raw)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			ctx = identity.WithReportInterval(ctx, interval)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
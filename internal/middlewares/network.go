@@ -1,39 +1,38 @@
 package middlewares
 
 import (
-	"net"
 	"net/http"
+
+	"metrix/internal/netpolicy"
+	"metrix/pkg/logger"
 )
 
-var subnet *net.IPNet
+var policy *netpolicy.Policy
 
-func InitSubnetMiddleware(s *net.IPNet) {
-	subnet = s
+// InitSubnetMiddleware - the function that sets the trusted network policy for SubnetMiddleware.
+func InitSubnetMiddleware(p *netpolicy.Policy) {
+	policy = p
 }
 
+// SubnetMiddleware - the net/http middleware function that rejects denied clients and
+// clients outside of the allowed networks, the client address is taken from the trusted proxy headers
+// or from the connection.
 func SubnetMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if subnet == nil {
-			next.ServeHTTP(w, r)
-		} else {
-			reqIPAddr := r.Header.Get("X-Real-IP")
-			if reqIPAddr == "" {
-				http.Error(w, "empty x-real-ip header", http.StatusForbidden)
-				return
-			}
-
-			ipAddr, _, err := net.ParseCIDR(reqIPAddr)
-			if ipAddr == nil {
-				http.Error(w, err.Error(), http.StatusForbidden)
-				return
-			}
-
-			if subnet.Contains(ipAddr) {
-				next.ServeHTTP(w, r)
-			} else {
-				http.Error(w, "subnet not verified", http.StatusForbidden)
-				return
-			}
+		if ip, err := policy.Check(r.Header.Get, r.RemoteAddr); err != nil {
+			logger.Warn(
+				r.Context(),
+				"request blocked by network policy",
+				"transport", "http",
+				"remote_addr", r.RemoteAddr,
+				"client_ip", ip.String(),
+				"url", r.URL.String(),
+				"reason", err.Error(),
+			)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
 		}
+
+		next.ServeHTTP(w, r)
 	})
 }
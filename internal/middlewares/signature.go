@@ -3,11 +3,10 @@ package middlewares
 import (
 	"bytes"
 	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"io"
 
+	"metrix/pkg/crypto"
 	"metrix/pkg/logger"
 
 	"net/http"
@@ -23,7 +22,7 @@ func SetSignKey(key string) {
 // SignatureMiddleware - the net/http middleware function to signt http content.
 func SignatureMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		hashSum := r.Header.Get("HashSHA256")
+		hashSum := r.Header.Get(crypto.SignatureHeader)
 		if r.Method == http.MethodPost && hashSum != "" && signKey != "" {
 			bodyBytes, err := io.ReadAll(r.Body)
 			if err != nil {
@@ -40,13 +39,11 @@ func SignatureMiddleware(next http.Handler) http.Handler {
 			}
 			r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
-			h := hmac.New(sha256.New, []byte(signKey))
-			h.Write(bodyBytes)
-			signature := hex.EncodeToString(h.Sum(nil))
+			signature := crypto.Sign(signKey, bodyBytes)
 
-			w.Header().Add("HashSHA256", signature)
+			w.Header().Add(crypto.SignatureHeader, signature)
 
-			if signature != hashSum {
+			if !hmac.Equal([]byte(signature), []byte(hashSum)) {
 				logger.Warn(
 					r.Context(),
 					fmt.Sprintf(
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AgentLabel - the label the server attaches to metrics reported by an identified agent.
+const AgentLabel = "agent"
+
+// AgentStatus - the string-based type for agent liveness statuses.
+type AgentStatus string
+
+// AgentStatus constants for HealthyAgentStatus, LateAgentStatus and DeadAgentStatus.
+const (
+	HealthyAgentStatus AgentStatus = "healthy"
+	LateAgentStatus    AgentStatus = "late"
+	DeadAgentStatus    AgentStatus = "dead"
+)
+
+// DefaultReportInterval - the report interval assumed for agents that have not declared one.
+const DefaultReportInterval = 10 * time.Second
+
+// Agent liveness is judged by the number of report intervals missed since the agent was last
+// seen: up to lateIntervals it is healthy, up to deadIntervals it is late, then it is dead.
+const (
+	lateIntervals = 2
+	deadIntervals = 5
+)
+
+// Agent - the structure for a known reporting agent.
+// ReportInterval is the interval the agent declared, in seconds.
+type Agent struct {
+	ID             string      `json:"id"              db:"id"`
+	LastSeen       time.Time   `json:"last_seen"       db:"last_seen"`
+	ReportInterval int64       `json:"report_interval" db:"report_interval"`
+	Status         AgentStatus `json:"status"          db:"-"`
+}
+
+// StatusAt - the method that computes agent liveness status at the moment now.
+func (a *Agent) StatusAt(now time.Time) AgentStatus {
+	interval := time.Duration(a.ReportInterval) * time.Second
+	if interval <= 0 {
+		interval = DefaultReportInterval
+	}
+
+	elapsed := now.Sub(a.LastSeen)
+	switch {
+	case elapsed <= lateIntervals*interval:
+		return HealthyAgentStatus
+	case elapsed <= deadIntervals*interval:
+		return LateAgentStatus
+	default:
+		return DeadAgentStatus
+	}
+}
+
+// WithAgent - the method that attributes the metric to the agent by setting the agent label,
+// a label set by the sender is replaced, so an agent can not write into series of another one.
+func (m *Metric) WithAgent(agentID string) {
+	if agentID == "" || m.Labels[AgentLabel] == agentID {
+		return
+	}
+
+	labels := make(Labels, len(m.Labels)+1)
+	for k, v := range m.Labels {
+		labels[k] = v
+	}
+	labels[AgentLabel] = agentID
+	m.Labels = labels
+}
+
+// IsAgentSeries - the function that checks the series key differs from the series of id and
+// labels only by the agent label, labels must not contain the agent label themselves.
+func IsAgentSeries(key string, id string, labels Labels) bool {
+	const placeholder = "\x00"
+
+	withAgent := make(Labels, len(labels)+1)
+	for k, v := range labels {
+		withAgent[k] = v
+	}
+	withAgent[AgentLabel] = placeholder
+
+	prefix, suffix, _ := strings.Cut(SeriesKey(id, withAgent), strconv.Quote(placeholder))
+	if len(key) < len(prefix)+len(suffix) || !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, suffix) {
+		return false
+	}
+
+	agentID, err := strconv.Unquote(key[len(prefix) : len(key)-len(suffix)])
+	if err != nil {
+		return false
+	}
+	withAgent[AgentLabel] = agentID
+
+	return SeriesKey(id, withAgent) == key
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// DefaultHistogramBounds - the bucket upper bounds used when a histogram is created from a single
+// observation without explicit bounds.
+var DefaultHistogramBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Histogram - the structure for a bucketed distribution of observed values.
+// Counts holds per-bucket (non-cumulative) counts, the last element is the +Inf bucket,
+// so len(Counts) == len(Bounds)+1.
+type Histogram struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []int64   `json:"counts"`
+	Sum    float64   `json:"sum"`
+	Count  int64     `json:"count"`
+}
+
+// NewHistogram - the builder function for an empty Histogram with given bucket bounds.
+func NewHistogram(bounds []float64) *Histogram {
+	if len(bounds) == 0 {
+		bounds = DefaultHistogramBounds
+	}
+
+	return &Histogram{
+		Bounds: slices.Clone(bounds),
+		Counts: make([]int64, len(bounds)+1),
+	}
+}
+
+// Validate - the method that checks histogram structure consistency.
+func (h *Histogram) Validate() error {
+	if len(h.Counts) != len(h.Bounds)+1 {
+		return fmt.Errorf("expected %d bucket counts, got %d", len(h.Bounds)+1, len(h.Counts))
+	}
+
+	if !sort.Float64sAreSorted(h.Bounds) {
+		return errors.New("bucket bounds must be sorted in ascending order")
+	}
+
+	var total int64
+	for _, c := range h.Counts {
+		if c < 0 {
+			return errors.New("bucket counts must not be negative")
+		}
+		total += c
+	}
+
+	if total != h.Count {
+		return fmt.Errorf("count %d does not match bucket counts sum %d", h.Count, total)
+	}
+
+	return nil
+}
+
+// Observe - the method that adds a single value to the histogram.
+func (h *Histogram) Observe(value float64) {
+	idx := sort.SearchFloat64s(h.Bounds, value)
+	h.Counts[idx]++
+	h.Sum += value
+	h.Count++
+}
+
+// Merge - the method that builds a new histogram combining h and other.
+// Histograms with different bounds cannot be combined, in this case other
+// replaces h as the bucket layout has been reconfigured by the sender.
+func (h *Histogram) Merge(other *Histogram) *Histogram {
+	if other == nil {
+		return h.clone()
+	}
+
+	if h == nil || !slices.Equal(h.Bounds, other.Bounds) {
+		return other.clone()
+	}
+
+	merged := h.clone()
+	for i, c := range other.Counts {
+		merged.Counts[i] += c
+	}
+	merged.Sum += other.Sum
+	merged.Count += other.Count
+
+	return merged
+}
+
+func (h *Histogram) clone() *Histogram {
+	if h == nil {
+		return nil
+	}
+
+	return &Histogram{
+		Bounds: slices.Clone(h.Bounds),
+		Counts: slices.Clone(h.Counts),
+		Sum:    h.Sum,
+		Count:  h.Count,
+	}
+}
+
+// Value - the method to match driver.Valuer interface, histograms are stored as json.
+func (h Histogram) Value() (driver.Value, error) {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal histogram: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// Scan - the method to match sql.Scanner interface.
+func (h *Histogram) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported histogram source type %T", src)
+	}
+
+	if err := json.Unmarshal(data, h); err != nil {
+		return fmt.Errorf("failed to unmarshal histogram: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Labels - the key/value set that together with the metric ID identifies a series.
+type Labels map[string]string
+
+// Validate - the method that checks label names.
+func (l Labels) Validate() error {
+	for k := range l {
+		if !labelNameRe.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+
+	return nil
+}
+
+// String - the method that renders labels in canonical form, e.g. {dc="eu",host="h42"},
+// names are sorted and values quoted, empty set is rendered as an empty string.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(l))
+	for k := range l {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(l[k]))
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+// Matches - the method that checks whether labels contain every pair of the selector.
+func (l Labels) Matches(selector Labels) bool {
+	for k, v := range selector {
+		if lv, ok := l[k]; !ok || lv != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Value - the method to match driver.Valuer interface, labels are stored as json.
+func (l Labels) Value() (driver.Value, error) {
+	if l == nil {
+		return "{}", nil
+	}
+
+	data, err := json.Marshal(map[string]string(l))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal labels: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// Scan - the method to match sql.Scanner interface.
+func (l *Labels) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported labels source type %T", src)
+	}
+
+	labels := Labels{}
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return fmt.Errorf("failed to unmarshal labels: %w", err)
+	}
+
+	if len(labels) == 0 {
+		labels = nil
+	}
+	*l = labels
+
+	return nil
+}
+
+// SeriesKey - the function that builds the identity of a series from metric ID and labels,
+// for metrics without labels the key is equal to the ID.
+func SeriesKey(id string, labels Labels) string {
+	return id + labels.String()
+}
@@ -2,48 +2,100 @@
 package model
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 // MType - the string-based type for metric types.
 type MType string
 
-// MType constants for GaugeType and CounterType.
+// MType constants for GaugeType, CounterType, HistogramType and SummaryType.
 const (
-	GaugeType   MType = "gauge"
-	CounterType MType = "counter"
+	GaugeType     MType = "gauge"
+	CounterType   MType = "counter"
+	HistogramType MType = "histogram"
+	SummaryType   MType = "summary"
 )
 
 // Metric - the structure for metric object serialisation.
+// A metric series is identified by its ID together with Labels, see SeriesKey.
+// Quantiles is a request-only field listing quantiles to be estimated for a summary.
 type Metric struct {
-	ID    string   `json:"id"              db:"id"      validate:"required"                       goqu:"skipupdate"`
-	MType MType    `json:"type"            db:"mtype"   validate:"required,oneof=counter gauge"`
-	Delta *int64   `json:"delta,omitempty" db:"delta"`
-	Value *float64 `json:"value,omitempty" db:"value"`
+	ID        string     `json:"id"                  db:"id"        validate:"required"                                         goqu:"skipupdate"`
+	MType     MType      `json:"type"                db:"mtype"     validate:"required,oneof=counter gauge histogram summary"`
+	Labels    Labels     `json:"labels,omitempty"    db:"labels"                                                                goqu:"skipupdate"`
+	Delta     *int64     `json:"delta,omitempty"     db:"delta"`
+	Value     *float64   `json:"value,omitempty"     db:"value"`
+	Histogram *Histogram `json:"histogram,omitempty" db:"histogram"`
+	Summary   *Summary   `json:"summary,omitempty"   db:"summary"`
+	Quantiles []float64  `json:"quantiles,omitempty" db:"-"`
+}
+
+// SeriesKey - the method that returns the identity of the metric series.
+func (m *Metric) SeriesKey() string {
+	return SeriesKey(m.ID, m.Labels)
 }
 
 // SetValue - the method that allows to encapsulate value set logic for different types.
-func (m *Metric) SetValue(delta *int64, value *float64) {
+// For histograms and summaries an incoming distribution is merged into the current one
+// and a plain value is treated as a single observation.
+func (m *Metric) SetValue(in *Metric) {
 	if m.MType == GaugeType {
 		m.Delta = nil
-		m.Value = value
+		m.Value = in.Value
 		return
 	}
 
 	if m.MType == CounterType {
 		m.Value = nil
 
-		if m.Delta != nil && delta != nil {
-			newDelta := *m.Delta + *delta
+		if m.Delta != nil && in.Delta != nil {
+			newDelta := *m.Delta + *in.Delta
 			m.Delta = &newDelta
 			return
 		}
 
-		if delta != nil {
-			m.Delta = delta
+		if in.Delta != nil {
+			m.Delta = in.Delta
 			return
 		}
 	}
+
+	if m.MType == HistogramType {
+		m.Delta = nil
+
+		if in.Histogram != nil {
+			m.Histogram = m.Histogram.Merge(in.Histogram)
+		}
+
+		if in.Value != nil {
+			if m.Histogram == nil {
+				m.Histogram = NewHistogram(nil)
+			} else {
+				m.Histogram = m.Histogram.clone()
+			}
+			m.Histogram.Observe(*in.Value)
+		}
+
+		m.Value = nil
+	}
+
+	if m.MType == SummaryType {
+		m.Delta = nil
+
+		if in.Summary != nil {
+			m.Summary = m.Summary.Merge(in.Summary)
+		}
+
+		if in.Value != nil {
+			m.Summary = m.Summary.Observe(*in.Value)
+		}
+
+		m.Value = nil
+	}
 }
 
 // GetValue - the method that gets value for dedicated type.
@@ -56,5 +108,22 @@ func (m *Metric) GetValue() string {
 		return strconv.FormatInt(*m.Delta, 10)
 	}
 
+	if m.MType == HistogramType && m.Histogram != nil {
+		data, err := json.Marshal(m.Histogram)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+
+	if m.MType == SummaryType && m.Summary != nil {
+		lines := []string{}
+		for q, v := range m.Summary.Quantiles {
+			lines = append(lines, fmt.Sprintf("%s %s", q, strconv.FormatFloat(v, 'f', -1, 64)))
+		}
+		sort.Strings(lines)
+		return strings.Join(lines, "\n")
+	}
+
 	return ""
 }
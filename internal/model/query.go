@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// Aggregation - the string-based type for downsampling aggregation functions.
+type Aggregation string
+
+// Aggregation constants for AvgAggregation, MinAggregation, MaxAggregation and LastAggregation.
+const (
+	AvgAggregation  Aggregation = "avg"
+	MinAggregation  Aggregation = "min"
+	MaxAggregation  Aggregation = "max"
+	LastAggregation Aggregation = "last"
+)
+
+// RangeQuery - the structure for metric history query parameters.
+type RangeQuery struct {
+	ID          string        `validate:"required"`
+	Labels      Labels        `validate:"-"`
+	From        time.Time     `validate:"required"`
+	To          time.Time     `validate:"required,gtefield=From"`
+	Step        time.Duration `validate:"required,gt=0"`
+	Aggregation Aggregation   `validate:"required,oneof=avg min max last"`
+}
+
+// Point - the structure for a single downsampled point of metric history.
+type Point struct {
+	Timestamp time.Time `json:"ts"`
+	Value     float64   `json:"value"`
+}
+
+// RangeResult - the structure for metric history query result serialisation.
+type RangeResult struct {
+	ID          string      `json:"id"`
+	Labels      Labels      `json:"labels,omitempty"`
+	Aggregation Aggregation `json:"agg"`
+	Step        float64     `json:"step"`
+	Points      []Point     `json:"points"`
+}
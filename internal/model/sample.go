@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+// Sample - the structure for a single point of metric history of a series.
+// Gauges keep their value and counters their accumulated delta, histograms and
+// summaries are reduced to the observations count (Delta) and their sum (Value).
+type Sample struct {
+	ID        string    `json:"id"               db:"id"`
+	MType     MType     `json:"type"             db:"mtype"`
+	Labels    Labels    `json:"labels,omitempty" db:"labels"`
+	Timestamp time.Time `json:"ts"               db:"ts"`
+	Delta     *int64    `json:"delta,omitempty"  db:"delta"`
+	Value     *float64  `json:"value,omitempty"  db:"value"`
+}
+
+// NewSample - the builder function for Sample of the metric state at the moment ts.
+func NewSample(m *Metric, ts time.Time) Sample {
+	sample := Sample{
+		ID:        m.ID,
+		MType:     m.MType,
+		Labels:    m.Labels,
+		Timestamp: ts,
+	}
+
+	switch m.MType {
+	case GaugeType:
+		sample.Value = m.Value
+	case CounterType:
+		sample.Delta = m.Delta
+	case HistogramType:
+		if m.Histogram != nil {
+			count, sum := m.Histogram.Count, m.Histogram.Sum
+			sample.Delta, sample.Value = &count, &sum
+		}
+	case SummaryType:
+		if m.Summary != nil && m.Summary.Sketch != nil {
+			count, sum := m.Summary.Sketch.Count, m.Summary.Sketch.Sum
+			sample.Delta, sample.Value = &count, &sum
+		}
+	}
+
+	return sample
+}
+
+// Float - the method that returns the sample as a single number: gauge value,
+// counter delta or the observations count for histograms and summaries.
+func (s *Sample) Float() float64 {
+	if s.MType == GaugeType && s.Value != nil {
+		return *s.Value
+	}
+
+	if s.Delta != nil {
+		return float64(*s.Delta)
+	}
+
+	return 0
+}
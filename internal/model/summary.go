@@ -0,0 +1,156 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"metrix/pkg/sketch"
+)
+
+// DefaultQuantiles - the quantiles reported for a summary when none were requested.
+var DefaultQuantiles = []float64{0.5, 0.9, 0.99}
+
+// Summary - the structure for a distribution of observed values kept as a mergeable
+// quantile sketch, so summaries reported by many agents can be combined on the server.
+type Summary struct {
+	Sketch    *sketch.DDSketch   `json:"sketch"`
+	Quantiles map[string]float64 `json:"quantiles,omitempty"`
+}
+
+// NewSummary - the builder function for an empty Summary.
+func NewSummary() *Summary {
+	return &Summary{Sketch: sketch.NewDefault()}
+}
+
+// Validate - the method that checks summary structure consistency.
+func (s *Summary) Validate() error {
+	if s.Sketch == nil {
+		return errors.New("summary sketch is missing")
+	}
+
+	if err := s.Sketch.Validate(); err != nil {
+		return fmt.Errorf("invalid summary sketch: %w", err)
+	}
+
+	return nil
+}
+
+// Observe - the method that builds a new summary with an additional value observed.
+func (s *Summary) Observe(value float64) *Summary {
+	observed := s.clone()
+	if observed == nil {
+		observed = NewSummary()
+	}
+	observed.Sketch.Add(value)
+
+	return observed
+}
+
+// Merge - the method that builds a new summary combining s and other.
+// Sketches with different accuracy cannot be combined, in this case other
+// replaces s as the sketch has been reconfigured by the sender.
+func (s *Summary) Merge(other *Summary) *Summary {
+	if other == nil {
+		return s.clone()
+	}
+
+	merged := s.clone()
+	if merged == nil || merged.Sketch.Merge(other.Sketch) != nil {
+		return other.clone()
+	}
+
+	return merged
+}
+
+// WithQuantiles - the method that builds a copy of the summary with Quantiles filled
+// by estimations for requested values.
+func (s *Summary) WithQuantiles(quantiles []float64) (*Summary, error) {
+	if len(quantiles) == 0 {
+		quantiles = DefaultQuantiles
+	}
+
+	out := &Summary{
+		Sketch:    s.Sketch,
+		Quantiles: make(map[string]float64, len(quantiles)),
+	}
+	for _, q := range quantiles {
+		if s.Sketch == nil || s.Sketch.Count == 0 {
+			break
+		}
+
+		v, err := s.Sketch.Quantile(q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate quantile: %w", err)
+		}
+		out.Quantiles[FormatQuantile(q)] = v
+	}
+
+	return out, nil
+}
+
+func (s *Summary) clone() *Summary {
+	if s == nil || s.Sketch == nil {
+		return nil
+	}
+
+	return &Summary{Sketch: s.Sketch.Clone()}
+}
+
+// FormatQuantile - the function that renders quantile as a map key.
+func FormatQuantile(q float64) string {
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
+
+// ParseQuantiles - the function that parses comma separated quantiles, e.g. "0.5,0.99".
+func ParseQuantiles(raw string) ([]float64, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	quantiles := []float64{}
+	for _, part := range strings.Split(raw, ",") {
+		q, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse quantile: %w", err)
+		}
+		if q < 0 || q > 1 {
+			return nil, fmt.Errorf("quantile must be in [0, 1], got %v", q)
+		}
+		quantiles = append(quantiles, q)
+	}
+
+	return quantiles, nil
+}
+
+// Value - the method to match driver.Valuer interface, only the sketch is stored as json.
+func (s Summary) Value() (driver.Value, error) {
+	data, err := json.Marshal(Summary{Sketch: s.Sketch})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal summary: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// Scan - the method to match sql.Scanner interface.
+func (s *Summary) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported summary source type %T", src)
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return fmt.Errorf("failed to unmarshal summary: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+// Module "netpolicy" describes the trusted network policy shared by the HTTP and gRPC transports.
+package netpolicy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrUntrusted - the error returned for clients outside of the allowed networks.
+var ErrUntrusted = errors.New("client address is not trusted")
+
+// ErrDenied - the error returned for clients from the denied networks.
+var ErrDenied = errors.New("client address is denied")
+
+// rules - the structure that holds a consistent snapshot of the policy lists.
+type rules struct {
+	allow   []*net.IPNet
+	deny    []*net.IPNet
+	proxies []*net.IPNet
+	headers []string
+}
+
+// Policy - the structure that holds allowed and denied networks and the proxy headers
+// the client address is taken from. Deny entries take precedence over allowed ones.
+// The headers are only honoured for connections from the trusted proxies networks.
+// A nil or empty policy trusts every client. The lists may be replaced at runtime.
+type Policy struct {
+	rules atomic.Pointer[rules]
+}
+
+// New - the builder function for Policy. Networks are CIDRs or plain IPv4/IPv6
+// addresses, headers are checked in the given order before the peer address.
+func New(allow []string, deny []string, proxies []string, headers []string) (*Policy, error) {
+	p := &Policy{}
+	if err := p.Update(allow, deny, proxies, headers); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Parse - the function that builds Policy from comma separated lists of networks and headers.
+func Parse(allow string, deny string, proxies string, headers string) (*Policy, error) {
+	return New(splitList(allow), splitList(deny), splitList(proxies), splitList(headers))
+}
+
+// Update - the method that atomically replaces the policy lists, the policy is left
+// untouched when any of the networks is invalid.
+func (p *Policy) Update(allow []string, deny []string, proxies []string, headers []string) error {
+	r := &rules{}
+
+	var err error
+	if r.allow, err = parseNetworks(allow); err != nil {
+		return err
+	}
+	if r.deny, err = parseNetworks(deny); err != nil {
+		return err
+	}
+	if r.proxies, err = parseNetworks(proxies); err != nil {
+		return err
+	}
+
+	for _, header := range headers {
+		if header = strings.TrimSpace(header); header != "" {
+			r.headers = append(r.headers, header)
+		}
+	}
+
+	p.rules.Store(r)
+
+	return nil
+}
+
+// UpdateList - the method that replaces the policy lists given as comma separated strings.
+func (p *Policy) UpdateList(allow string, deny string, proxies string, headers string) error {
+	return p.Update(splitList(allow), splitList(deny), splitList(proxies), splitList(headers))
+}
+
+// load - the method that returns the current policy lists.
+func (p *Policy) load() *rules {
+	if p == nil {
+		return &rules{}
+	}
+
+	if r := p.rules.Load(); r != nil {
+		return r
+	}
+
+	return &rules{}
+}
+
+// splitList - the function that splits comma separated list.
+func splitList(list string) []string {
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+
+	return strings.Split(list, ",")
+}
+
+// parseNetworks - the function that parses the list of networks skipping empty entries.
+func parseNetworks(list []string) ([]*net.IPNet, error) {
+	networks := []*net.IPNet{}
+
+	for _, raw := range list {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		network, err := parseNetwork(raw)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// parseNetwork - the function that parses CIDR, a plain address is turned into a single host network.
+func parseNetwork(raw string) (*net.IPNet, error) {
+	if strings.Contains(raw, "/") {
+		_, network, err := net.ParseCIDR(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted network %q: %w", raw, err)
+		}
+		return network, nil
+	}
+
+	ip := net.ParseIP(raw)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid trusted network %q", raw)
+	}
+
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// Enabled - the method that reports whether the policy restricts clients at all.
+func (p *Policy) Enabled() bool {
+	r := p.load()
+	return len(r.allow) > 0 || len(r.deny) > 0
+}
+
+// Headers - the method that returns trusted proxy headers.
+func (p *Policy) Headers() []string {
+	return p.load().headers
+}
+
+// Contains - the method that checks the address belongs to one of the allowed networks.
+func (p *Policy) Contains(ip net.IP) bool {
+	return contains(p.load().allow, ip)
+}
+
+// Denied - the method that checks the address belongs to one of the denied networks.
+func (p *Policy) Denied(ip net.IP) bool {
+	return contains(p.load().deny, ip)
+}
+
+// String - the method that renders the policy lists for logs.
+func (p *Policy) String() string {
+	r := p.load()
+
+	return fmt.Sprintf("allow=%v deny=%v proxies=%v headers=%v", r.allow, r.deny, r.proxies, r.headers)
+}
+
+// contains - the function that checks the address belongs to one of the networks.
+func contains(networks []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP - the method that resolves the client address. Proxy headers are only honoured
+// when the peer is a trusted proxy, otherwise the peer address is used. The first non-empty
+// header wins, for a list of addresses the client is the last one not being a trusted proxy,
+// as every proxy appends the address it received the request from.
+func (p *Policy) ClientIP(header func(name string) string, peerAddr string) (net.IP, error) {
+	ip, err := ParseHost(peerAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	r := p.load()
+	if !contains(r.proxies, ip) {
+		return ip, nil
+	}
+
+	for _, name := range r.headers {
+		value := header(name)
+		if value == "" {
+			continue
+		}
+
+		hops := strings.Split(value, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := ParseHost(strings.TrimSpace(hops[i]))
+			if err != nil {
+				return nil, err
+			}
+			if i == 0 || !contains(r.proxies, hop) {
+				return hop, nil
+			}
+		}
+	}
+
+	return ip, nil
+}
+
+// Check - the method that verifies the client is trusted: it must not be denied and,
+// when allowed networks are set, must belong to one of them. Every client passes a
+// disabled policy.
+func (p *Policy) Check(header func(name string) string, peerAddr string) (net.IP, error) {
+	r := p.load()
+	if len(r.allow) == 0 && len(r.deny) == 0 {
+		return nil, nil
+	}
+
+	ip, err := p.ClientIP(header, peerAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if contains(r.deny, ip) {
+		return ip, fmt.Errorf("%w: %s", ErrDenied, ip)
+	}
+
+	if len(r.allow) > 0 && !contains(r.allow, ip) {
+		return ip, fmt.Errorf("%w: %s", ErrUntrusted, ip)
+	}
+
+	return ip, nil
+}
+
+// ParseHost - the function that extracts IP from an address in any of the forms
+// "ip", "ip:port", "[ipv6]:port", "ipv6%zone" or "ip/prefix".
+func ParseHost(addr string) (net.IP, error) {
+	host := addr
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		host = host[:i]
+	}
+	if i := strings.IndexByte(host, '/'); i >= 0 {
+		host = host[:i]
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid client address %q", addr)
+	}
+
+	return ip, nil
+}
@@ -0,0 +1,151 @@
+package netpolicy
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestParseHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		addr    string
+		want    string
+		wantErr bool
+	}{
+		{name: "Test 1", addr: "192.168.1.5", want: "192.168.1.5"},
+		{name: "Test 2", addr: "192.168.1.5:43512", want: "192.168.1.5"},
+		{name: "Test 3", addr: "[2001:db8::1]:43512", want: "2001:db8::1"},
+		{name: "Test 4", addr: "2001:db8::1", want: "2001:db8::1"},
+		{name: "Test 5", addr: "fe80::1%eth0", want: "fe80::1"},
+		{name: "Test 6", addr: "192.168.1.5/24", want: "192.168.1.5"},
+		{name: "Test 7", addr: "bufconn", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHost(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHost() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !got.Equal(net.ParseIP(tt.want)) {
+				t.Errorf("ParseHost() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	policy, err := Parse(
+		"192.168.1.0/24, 2001:db8::/32,10.0.0.7",
+		"192.168.1.66,2001:db8:dead::/48",
+		"127.0.0.1,10.1.0.0/16",
+		"X-Real-IP,X-Forwarded-For",
+	)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		peer    string
+		wantErr error
+	}{
+		{name: "Test 1", peer: "192.168.1.10:5000"},
+		{name: "Test 2", peer: "[2001:db8::42]:5000"},
+		{name: "Test 3", peer: "[::ffff:192.168.1.10]:5000"},
+		{name: "Test 4", peer: "10.0.0.7:5000"},
+		{name: "Test 5", peer: "10.0.0.8:5000", wantErr: ErrUntrusted},
+		{name: "Test 6", peer: "[2001:db9::1]:5000", wantErr: ErrUntrusted},
+		{name: "Test 7", headers: map[string]string{"X-Real-IP": "192.168.1.20"}, peer: "127.0.0.1:5000"},
+		{
+			name:    "Test 8",
+			headers: map[string]string{"X-Forwarded-For": "2001:db8::5, 127.0.0.1"},
+			peer:    "127.0.0.1:5000",
+		},
+		{
+			name:    "Test 9",
+			headers: map[string]string{"X-Real-IP": "172.16.0.1"},
+			peer:    "127.0.0.1:5000",
+			wantErr: ErrUntrusted,
+		},
+		{name: "Test 10", peer: "192.168.1.66:5000", wantErr: ErrDenied},
+		{name: "Test 11", peer: "[2001:db8:dead::1]:5000", wantErr: ErrDenied},
+		{
+			name:    "Test 12",
+			headers: map[string]string{"X-Real-IP": "192.168.1.20"},
+			peer:    "10.0.0.8:5000",
+			wantErr: ErrUntrusted,
+		},
+		{
+			name:    "Test 13",
+			headers: map[string]string{"X-Forwarded-For": "192.168.1.20, 172.16.0.1, 10.1.2.3"},
+			peer:    "127.0.0.1:5000",
+			wantErr: ErrUntrusted,
+		},
+		{
+			name:    "Test 14",
+			headers: map[string]string{"X-Forwarded-For": "172.16.0.1, 192.168.1.20, 10.1.2.3"},
+			peer:    "127.0.0.1:5000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := policy.Check(func(name string) string { return tt.headers[name] }, tt.peer)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicy_Disabled(t *testing.T) {
+	policies := []*Policy{nil, {}}
+	for _, policy := range policies {
+		if _, err := policy.Check(func(string) string { return "" }, "bufconn"); err != nil {
+			t.Errorf("Check() error = %v, want nil for disabled policy", err)
+		}
+	}
+
+	if _, err := Parse("192.168.1.0/33", "", "", ""); err == nil {
+		t.Errorf("Parse() expected error for invalid network")
+	}
+}
+
+func TestPolicy_UpdateList(t *testing.T) {
+	policy, err := Parse("", "10.0.0.0/8", "", "")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		update    bool
+		allow     string
+		deny      string
+		peer      string
+		wantErr   error
+		wantFails bool
+	}{
+		{name: "Test 1", peer: "10.1.1.1:5000", wantErr: ErrDenied},
+		{name: "Test 2", peer: "172.16.0.1:5000"},
+		{name: "Test 3", update: true, allow: "172.16.0.0/12", peer: "172.16.5.5:5000"},
+		{name: "Test 4", peer: "10.1.1.1:5000", wantErr: ErrUntrusted},
+		{name: "Test 5", update: true, allow: "not-a-network", peer: "172.16.5.5:5000", wantFails: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.update {
+				err := policy.UpdateList(tt.allow, tt.deny, "", "")
+				if (err != nil) != tt.wantFails {
+					t.Fatalf("UpdateList() error = %v, wantFails %v", err, tt.wantFails)
+				}
+			}
+
+			_, err := policy.Check(func(string) string { return "" }, tt.peer)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"metrix/internal/model"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+const agentTName = "mtr_agents"
+
+// AgentRepositoryImpl - the structure for implementation of the AgentRepository concept.
+type AgentRepositoryImpl struct {
+	gr *Group
+}
+
+// NewAgentRepository - the builder function for AgentRepositoryImpl.
+func NewAgentRepository(db *Group) *AgentRepositoryImpl {
+	return &AgentRepositoryImpl{gr: db}
+}
+
+// Touch - the method to register the agent or refresh its last seen time and declared
+// report interval, an undeclared interval keeps the stored one.
+func (r *AgentRepositoryImpl) Touch(
+	ctx context.Context,
+	agent *model.Agent,
+) error {
+	qu, _, err := r.gr.dialect().
+		Insert(agentTName).
+		Rows(agent).
+		ToSQL()
+	if err != nil {
+		return fmt.Errorf("touch agent error during query building: %w", err)
+	}
+	qu += " ON CONFLICT (id) DO UPDATE SET" +
+		" last_seen = CASE WHEN excluded.last_seen > mtr_agents.last_seen" +
+		" THEN excluded.last_seen ELSE mtr_agents.last_seen END," +
+		" report_interval = CASE WHEN excluded.report_interval > 0" +
+		" THEN excluded.report_interval ELSE mtr_agents.report_interval END"
+
+	if _, err := r.gr.DB.ExecContext(ctx, qu); err != nil {
+		return fmt.Errorf("failed to touch agent: %w", err)
+	}
+
+	return nil
+}
+
+// ReadAll - the method to read all known agents ordered by ID.
+func (r *AgentRepositoryImpl) ReadAll(ctx context.Context) (*[]model.Agent, error) {
+	qu, _, err := r.gr.dialect().
+		Select(&model.Agent{}).
+		From(agentTName).
+		Order(goqu.C("id").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, fmt.Errorf("read agents error during query building: %w", err)
+	}
+
+	rows, err := r.gr.DB.QueryxContext(ctx, qu)
+	if err != nil {
+		return nil, fmt.Errorf("read agents error during querying: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			return
+		}
+	}()
+
+	agents := []model.Agent{}
+	for rows.Next() {
+		agent := model.Agent{}
+		if err := rows.StructScan(&agent); err != nil {
+			return nil, fmt.Errorf("read agents error during scan rows: %w", err)
+		}
+		agents = append(agents, agent)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read agents error during querying: %w", err)
+	}
+
+	return &agents, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"metrix/internal/model"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// agentBucket - the bucket of the embedded database keeping agents by ID.
+var agentBucket = []byte("agents")
+
+// BoltAgentRepository - the structure for implementation of the AgentRepository concept
+// on top of the embedded bbolt database.
+type BoltAgentRepository struct {
+	gr *Group
+}
+
+// NewBoltAgentRepository - the builder function for BoltAgentRepository.
+func NewBoltAgentRepository(db *Group) *BoltAgentRepository {
+	return &BoltAgentRepository{gr: db}
+}
+
+// Touch - the method to register the agent or refresh its last seen time and declared
+// report interval, an undeclared interval keeps the stored one.
+func (r *BoltAgentRepository) Touch(
+	ctx context.Context,
+	agent *model.Agent,
+) error {
+	err := r.gr.KV.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(agentBucket)
+		if err != nil {
+			return err
+		}
+
+		stored := model.Agent{ID: agent.ID}
+		if data := b.Get([]byte(agent.ID)); data != nil {
+			if err := json.Unmarshal(data, &stored); err != nil {
+				return err
+			}
+		}
+
+		if agent.LastSeen.After(stored.LastSeen) {
+			stored.LastSeen = agent.LastSeen
+		}
+		if agent.ReportInterval > 0 {
+			stored.ReportInterval = agent.ReportInterval
+		}
+
+		data, err := json.Marshal(stored)
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(agent.ID), data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to touch agent: %w", err)
+	}
+
+	return nil
+}
+
+// ReadAll - the method to read all known agents ordered by ID.
+func (r *BoltAgentRepository) ReadAll(ctx context.Context) (*[]model.Agent, error) {
+	agents := []model.Agent{}
+
+	err := r.gr.KV.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(agentBucket)
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(_, v []byte) error {
+			agent := model.Agent{}
+			if err := json.Unmarshal(v, &agent); err != nil {
+				return err
+			}
+			agents = append(agents, agent)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agents: %w", err)
+	}
+
+	return &agents, nil
+}
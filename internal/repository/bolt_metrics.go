@@ -0,0 +1,338 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"metrix/internal/model"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets of the embedded database: metrics are stored by the series key, every series has
+// a nested samples bucket keyed by the timestamp and a sequence number.
+var (
+	metricBucket = []byte("metrics")
+	sampleBucket = []byte("samples")
+)
+
+// sampleKeySize - the size of the sample key: unix nanoseconds and the sequence number.
+const sampleKeySize = 16
+
+// BoltMetricRepository - the structure for implementation of the MetricRepository concept
+// on top of the embedded bbolt database.
+type BoltMetricRepository struct {
+	gr        *Group
+	retention time.Duration
+}
+
+// NewBoltMetricRepository - the builder function for BoltMetricRepository.
+// The history of metric values is kept for the retention period, zero disables it.
+func NewBoltMetricRepository(db *Group, retention time.Duration) *BoltMetricRepository {
+	return &BoltMetricRepository{
+		gr:        db,
+		retention: retention,
+	}
+}
+
+// Create - the method to store the metric, an existing series is replaced.
+func (r *BoltMetricRepository) Create(
+	ctx context.Context,
+	metric *model.Metric,
+) (*model.Metric, error) {
+	if err := r.put([]model.Metric{*metric}); err != nil {
+		return nil, fmt.Errorf("failed to create metric: %w", err)
+	}
+
+	return metric, nil
+}
+
+// Read - the method to read the metric by the series key.
+func (r *BoltMetricRepository) Read(
+	ctx context.Context,
+	metricKey string,
+) (*model.Metric, error) {
+	var metric *model.Metric
+
+	err := r.gr.KV.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(metricBucket)
+		if b == nil {
+			return nil
+		}
+
+		data := b.Get([]byte(metricKey))
+		if data == nil {
+			return nil
+		}
+
+		metric = &model.Metric{}
+		return json.Unmarshal(data, metric)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metric: %w", err)
+	}
+
+	return metric, nil
+}
+
+// ReadIDs - the method that retrieves metrics series keys.
+func (r *BoltMetricRepository) ReadIDs(ctx context.Context) (*[]string, error) {
+	var ids []string
+
+	err := r.gr.KV.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(metricBucket)
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, _ []byte) error {
+			ids = append(ids, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ids: %w", err)
+	}
+
+	return &ids, nil
+}
+
+// ReadMany - the method to read metrics in batch by the series keys.
+func (r *BoltMetricRepository) ReadMany(
+	ctx context.Context,
+	metricKeys []string,
+) (*[]model.Metric, error) {
+	metrics := []model.Metric{}
+
+	err := r.gr.KV.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(metricBucket)
+		if b == nil {
+			return nil
+		}
+
+		for _, key := range metricKeys {
+			data := b.Get([]byte(key))
+			if data == nil {
+				continue
+			}
+
+			metric := model.Metric{}
+			if err := json.Unmarshal(data, &metric); err != nil {
+				return err
+			}
+			metrics = append(metrics, metric)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metrics: %w", err)
+	}
+
+	return &metrics, nil
+}
+
+// Update - the method to update the metric.
+func (r *BoltMetricRepository) Update(
+	ctx context.Context,
+	metric *model.Metric,
+) (*model.Metric, error) {
+	if err := r.put([]model.Metric{*metric}); err != nil {
+		return nil, fmt.Errorf("failed to update metric: %w", err)
+	}
+
+	return metric, nil
+}
+
+// UpsertMany - the method to insert/update metrics in a single transaction.
+func (r *BoltMetricRepository) UpsertMany(
+	ctx context.Context,
+	metrics []model.Metric,
+) (bool, error) {
+	if err := r.put(metrics); err != nil {
+		return false, fmt.Errorf("failed to upsert metrics: %w", err)
+	}
+
+	return true, nil
+}
+
+// put - the method that stores the metrics and their samples in a single transaction.
+func (r *BoltMetricRepository) put(metrics []model.Metric) error {
+	now := time.Now().UTC()
+
+	return r.gr.KV.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(metricBucket)
+		if err != nil {
+			return err
+		}
+
+		for i := range metrics {
+			data, err := json.Marshal(metrics[i])
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(metrics[i].SeriesKey()), data); err != nil {
+				return err
+			}
+		}
+
+		return r.insertSamples(tx, metrics, now)
+	})
+}
+
+// insertSamples - the method that appends the metrics states to their histories.
+func (r *BoltMetricRepository) insertSamples(tx *bolt.Tx, metrics []model.Metric, ts time.Time) error {
+	if r.retention <= 0 {
+		return nil
+	}
+
+	samples, err := tx.CreateBucketIfNotExists(sampleBucket)
+	if err != nil {
+		return err
+	}
+
+	for i := range metrics {
+		b, err := samples.CreateBucketIfNotExists([]byte(metrics[i].SeriesKey()))
+		if err != nil {
+			return err
+		}
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(model.NewSample(&metrics[i], ts))
+		if err != nil {
+			return err
+		}
+		if err := b.Put(sampleKey(ts, seq), data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sampleKey - the function that builds the sample key ordered by time, times before
+// the epoch are clamped to it.
+func sampleKey(ts time.Time, seq uint64) []byte {
+	var nanos uint64
+	if ts.After(time.Unix(0, 0)) {
+		nanos = uint64(ts.UnixNano())
+	}
+
+	key := make([]byte, sampleKeySize)
+	binary.BigEndian.PutUint64(key[:8], nanos)
+	binary.BigEndian.PutUint64(key[8:], seq)
+
+	return key
+}
+
+// Delete - the method to remove the metric and its history by the series key.
+func (r *BoltMetricRepository) Delete(
+	ctx context.Context,
+	metricKey string,
+) error {
+	err := r.gr.KV.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(metricBucket); b != nil {
+			if err := b.Delete([]byte(metricKey)); err != nil {
+				return err
+			}
+		}
+
+		if samples := tx.Bucket(sampleBucket); samples != nil && samples.Bucket([]byte(metricKey)) != nil {
+			return samples.DeleteBucket([]byte(metricKey))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete metric: %w", err)
+	}
+
+	return nil
+}
+
+// ReadRange - the method to read the metric series history within the time range.
+func (r *BoltMetricRepository) ReadRange(
+	ctx context.Context,
+	metricKey string,
+	from time.Time,
+	to time.Time,
+) (*[]model.Sample, error) {
+	samples := []model.Sample{}
+
+	err := r.gr.KV.View(func(tx *bolt.Tx) error {
+		parent := tx.Bucket(sampleBucket)
+		if parent == nil {
+			return nil
+		}
+		b := parent.Bucket([]byte(metricKey))
+		if b == nil {
+			return nil
+		}
+
+		last := sampleKey(to, 0)[:8]
+
+		c := b.Cursor()
+		for k, v := c.Seek(sampleKey(from, 0)); k != nil; k, v = c.Next() {
+			if bytes.Compare(k[:8], last) > 0 {
+				break
+			}
+
+			sample := model.Sample{}
+			if err := json.Unmarshal(v, &sample); err != nil {
+				return err
+			}
+			samples = append(samples, sample)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read range: %w", err)
+	}
+
+	return &samples, nil
+}
+
+// PeriodicCleanup - the method that periodically removes samples older than retention period.
+func (r *BoltMetricRepository) PeriodicCleanup(ctx context.Context) {
+	periodicCleanup(ctx, r.retention, r.cleanup)
+}
+
+func (r *BoltMetricRepository) cleanup(ctx context.Context) error {
+	cutoff := sampleKey(time.Now().UTC().Add(-r.retention), 0)
+
+	err := r.gr.KV.Update(func(tx *bolt.Tx) error {
+		samples := tx.Bucket(sampleBucket)
+		if samples == nil {
+			return nil
+		}
+
+		return samples.ForEachBucket(func(k []byte) error {
+			c := samples.Bucket(k).Cursor()
+			for key, _ := c.First(); key != nil && bytes.Compare(key, cutoff) < 0; key, _ = c.First() {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clean up samples: %w", err)
+	}
+
+	return nil
+}
+
+// PingDB - the method to check the embedded database is open.
+func (r *BoltMetricRepository) PingDB(ctx context.Context) bool {
+	return r.gr.KV.View(func(tx *bolt.Tx) error { return nil }) == nil
+}
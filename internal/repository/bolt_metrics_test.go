@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"metrix/internal/model"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltMetricRepository_Cleanup(t *testing.T) {
+	ctx := context.Background()
+
+	kv, err := bolt.Open(filepath.Join(t.TempDir(), "metrix.db"), 0o600, nil)
+	if err != nil {
+		t.Fatalf("failed to open bolt db: %s", err)
+	}
+	defer kv.Close()
+
+	repo := NewBoltMetricRepository(&Group{KV: kv}, time.Millisecond)
+	if _, err := repo.UpsertMany(ctx, []model.Metric{counter("PollCount", 1)}); err != nil {
+		t.Fatalf("UpsertMany() error = %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if _, err := repo.UpsertMany(ctx, []model.Metric{counter("PollCount", 2)}); err != nil {
+		t.Fatalf("UpsertMany() error = %v", err)
+	}
+
+	repo.retention = 100 * time.Millisecond
+	if err := repo.cleanup(ctx); err != nil {
+		t.Fatalf("cleanup() error = %v", err)
+	}
+
+	samples, err := repo.ReadRange(ctx, "PollCount", time.Time{}, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("ReadRange() error = %v", err)
+	}
+	if len(*samples) != 1 || *(*samples)[0].Delta != 2 {
+		t.Errorf("ReadRange() after cleanup = %+v, want the latest sample only", *samples)
+	}
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"metrix/internal/model"
+)
+
+// BroadcastRepository - the MetricRepository decorator that publishes every stored metric
+// to subscribers. Subscribers falling behind lose updates instead of blocking writers.
+type BroadcastRepository struct {
+	MetricRepository
+
+	mux         *sync.RWMutex
+	subscribers map[int]chan model.Metric
+	nextID      int
+}
+
+// NewBroadcastRepository - the builder function for BroadcastRepository.
+func NewBroadcastRepository(repo MetricRepository) *BroadcastRepository {
+	return &BroadcastRepository{
+		MetricRepository: repo,
+		mux:              &sync.RWMutex{},
+		subscribers:      make(map[int]chan model.Metric),
+	}
+}
+
+// Subscribe - the method that registers a subscriber with a buffer of given size,
+// the returned function cancels the subscription and closes the channel.
+func (b *BroadcastRepository) Subscribe(buffer int) (<-chan model.Metric, func()) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	id := b.nextID
+	b.nextID++
+
+	ch := make(chan model.Metric, buffer)
+	b.subscribers[id] = ch
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mux.Lock()
+			defer b.mux.Unlock()
+
+			delete(b.subscribers, id)
+			close(ch)
+		})
+	}
+
+	return ch, cancel
+}
+
+// Create - the method to insert metric and publish it.
+func (b *BroadcastRepository) Create(
+	ctx context.Context,
+	metric *model.Metric,
+) (*model.Metric, error) {
+	created, err := b.MetricRepository.Create(ctx, metric)
+	if err != nil {
+		return nil, err
+	}
+
+	b.publish(*created)
+
+	return created, nil
+}
+
+// Update - the method to update metric and publish it.
+func (b *BroadcastRepository) Update(
+	ctx context.Context,
+	metric *model.Metric,
+) (*model.Metric, error) {
+	updated, err := b.MetricRepository.Update(ctx, metric)
+	if err != nil {
+		return nil, err
+	}
+
+	if updated != nil {
+		b.publish(*updated)
+	}
+
+	return updated, nil
+}
+
+// UpsertMany - the method to insert/update metrics in batch and publish them.
+func (b *BroadcastRepository) UpsertMany(
+	ctx context.Context,
+	metrics []model.Metric,
+) (bool, error) {
+	status, err := b.MetricRepository.UpsertMany(ctx, metrics)
+	if err != nil {
+		return false, err
+	}
+
+	b.publish(metrics...)
+
+	return status, nil
+}
+
+func (b *BroadcastRepository) publish(metrics ...model.Metric) {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	for _, ch := range b.subscribers {
+		for _, m := range metrics {
+			select {
+			case ch <- m:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"metrix/internal/model"
+	"metrix/internal/storages"
+)
+
+func TestBroadcastRepository_UpsertMany(t *testing.T) {
+	ctx := context.Background()
+	b := NewBroadcastRepository(storages.NewInMemoryStorage(ctx, "", 0, false, 0, 1))
+
+	updates, cancel := b.Subscribe(1)
+	defer cancel()
+
+	value := float64(1)
+	metrics := []model.Metric{
+		{ID: "Alloc", MType: model.GaugeType, Value: &value},
+		{ID: "Frees", MType: model.GaugeType, Value: &value},
+	}
+	if _, err := b.UpsertMany(ctx, metrics); err != nil {
+		t.Fatalf("UpsertMany() error = %v", err)
+	}
+
+	if got := <-updates; got.ID != "Alloc" {
+		t.Errorf("first update = %s, want Alloc", got.ID)
+	}
+
+	select {
+	case got := <-updates:
+		t.Errorf("update %s must be dropped for a full subscriber", got.ID)
+	default:
+	}
+
+	cancel()
+	if _, ok := <-updates; ok {
+		t.Errorf("channel must be closed after cancel")
+	}
+}
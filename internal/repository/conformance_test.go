@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"metrix/internal/bootstrap"
+	"metrix/internal/config"
+	"metrix/internal/model"
+	"metrix/internal/storages"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// testDatabaseDSN - the environment variable with the Postgres DSN the conformance suite
+// runs against, the Postgres backend is skipped without it.
+const testDatabaseDSN = "TEST_DATABASE_DSN"
+
+// conformanceRetention - the history retention every backend is built with.
+const conformanceRetention = time.Hour
+
+// backend - the structure that builds fresh repositories of a single storage backend.
+type backend struct {
+	name string
+	open func(t *testing.T) *Group
+}
+
+func backends() []backend {
+	return []backend{
+		{
+			name: "memory",
+			open: func(t *testing.T) *Group {
+				return &Group{
+					MetricRepo: storages.NewInMemoryStorage(context.Background(), "", 0, false, conformanceRetention, 1),
+					AgentRepo:  storages.NewAgentMemoryStorage(),
+				}
+			},
+		},
+		{
+			name: "bolt",
+			open: func(t *testing.T) *Group {
+				kv, err := bolt.Open(filepath.Join(t.TempDir(), "metrix.db"), 0o600, nil)
+				if err != nil {
+					t.Fatalf("failed to open bolt db: %s", err)
+				}
+				t.Cleanup(func() { kv.Close() })
+
+				gr := &Group{KV: kv}
+				gr.MetricRepo = NewBoltMetricRepository(gr, conformanceRetention)
+				gr.AgentRepo = NewBoltAgentRepository(gr)
+				return gr
+			},
+		},
+		{
+			name: "sqlite",
+			open: func(t *testing.T) *Group {
+				return openSQL(t, "sqlite://"+filepath.Join(t.TempDir(), "metrix.db"))
+			},
+		},
+		{
+			name: "postgres",
+			open: func(t *testing.T) *Group {
+				dsn := os.Getenv(testDatabaseDSN)
+				if dsn == "" {
+					t.Skipf("%s is not set", testDatabaseDSN)
+				}
+
+				gr := openSQL(t, dsn)
+				if _, err := gr.DB.Exec("TRUNCATE mtr_metrics, mtr_samples, mtr_agents"); err != nil {
+					t.Fatalf("failed to truncate tables: %s", err)
+				}
+				return gr
+			},
+		},
+	}
+}
+
+// openSQL - the function that migrates the database and builds the SQL repositories on top of it.
+func openSQL(t *testing.T, dsn string) *Group {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	db, err := bootstrap.InitDB(ctx, &config.Postgres{
+		DSN:             dsn,
+		MaxOpenConn:     4,
+		IdleConn:        4,
+		PingInterval:    time.Minute,
+		MigrationFolder: "../../migrations",
+	})
+	if err != nil {
+		t.Fatalf("failed to init db: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	gr := &Group{DB: db}
+	gr.MetricRepo = NewMetricRepository(gr, conformanceRetention)
+	gr.AgentRepo = NewAgentRepository(gr)
+
+	return gr
+}
+
+func gauge(id string, value float64, labels model.Labels) model.Metric {
+	return model.Metric{ID: id, MType: model.GaugeType, Value: &value, Labels: labels}
+}
+
+func counter(id string, delta int64) model.Metric {
+	return model.Metric{ID: id, MType: model.CounterType, Delta: &delta}
+}
+
+// sameMetric - the function that compares metrics ignoring the representation of empty labels.
+func sameMetric(got *model.Metric, want model.Metric) bool {
+	if got == nil || got.SeriesKey() != want.SeriesKey() || got.MType != want.MType {
+		return false
+	}
+	if (got.Delta == nil) != (want.Delta == nil) || got.Delta != nil && *got.Delta != *want.Delta {
+		return false
+	}
+	if (got.Value == nil) != (want.Value == nil) || got.Value != nil && *got.Value != *want.Value {
+		return false
+	}
+
+	return true
+}
+
+func TestMetricRepository_Conformance(t *testing.T) {
+	for _, b := range backends() {
+		t.Run(b.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := b.open(t).MetricRepo
+
+			alloc := gauge("Alloc", 1.5, nil)
+			allocEU := gauge("Alloc", 2.5, model.Labels{"dc": "eu"})
+
+			if _, err := repo.Create(ctx, &alloc); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if _, err := repo.UpsertMany(ctx, []model.Metric{allocEU, counter("PollCount", 1)}); err != nil {
+				t.Fatalf("UpsertMany() error = %v", err)
+			}
+			if _, err := repo.UpsertMany(ctx, []model.Metric{counter("PollCount", 3)}); err != nil {
+				t.Fatalf("UpsertMany() error = %v", err)
+			}
+			updated := gauge("Alloc", 4.5, nil)
+			if _, err := repo.Update(ctx, &updated); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+
+			tests := []struct {
+				name  string
+				key   string
+				want  model.Metric
+				found bool
+			}{
+				{name: "Test 1", key: alloc.SeriesKey(), want: updated, found: true},
+				{name: "Test 2", key: allocEU.SeriesKey(), want: allocEU, found: true},
+				{name: "Test 3", key: "PollCount", want: counter("PollCount", 3), found: true},
+				{name: "Test 4", key: "Missing"},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					got, err := repo.Read(ctx, tt.key)
+					if err != nil {
+						t.Fatalf("Read() error = %v", err)
+					}
+					if (got != nil) != tt.found {
+						t.Fatalf("Read() = %v, found %v", got, tt.found)
+					}
+					if tt.found && !sameMetric(got, tt.want) {
+						t.Errorf("Read() = %+v, want %+v", got, tt.want)
+					}
+				})
+			}
+
+			ids, err := repo.ReadIDs(ctx)
+			if err != nil {
+				t.Fatalf("ReadIDs() error = %v", err)
+			}
+			sort.Strings(*ids)
+			wantIDs := []string{alloc.SeriesKey(), allocEU.SeriesKey(), "PollCount"}
+			sort.Strings(wantIDs)
+			if !slices.Equal(*ids, wantIDs) {
+				t.Errorf("ReadIDs() = %v, want %v", *ids, wantIDs)
+			}
+
+			many, err := repo.ReadMany(ctx, []string{"PollCount", "Missing", allocEU.SeriesKey()})
+			if err != nil {
+				t.Fatalf("ReadMany() error = %v", err)
+			}
+			if len(*many) != 2 {
+				t.Errorf("ReadMany() returned %d metrics, want 2", len(*many))
+			}
+
+			now := time.Now().UTC()
+			samples, err := repo.ReadRange(ctx, "PollCount", now.Add(-time.Minute), now.Add(time.Minute))
+			if err != nil {
+				t.Fatalf("ReadRange() error = %v", err)
+			}
+			if len(*samples) != 2 || *(*samples)[0].Delta != 1 || *(*samples)[1].Delta != 3 {
+				t.Errorf("ReadRange() = %+v, want deltas 1 and 3", *samples)
+			}
+			samples, err = repo.ReadRange(ctx, "PollCount", now.Add(time.Minute), now.Add(2*time.Minute))
+			if err != nil || len(*samples) != 0 {
+				t.Errorf("ReadRange() in the future = %v, %v, want empty", samples, err)
+			}
+
+			if err := repo.Delete(ctx, "PollCount"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if got, err := repo.Read(ctx, "PollCount"); err != nil || got != nil {
+				t.Errorf("Read() after Delete() = %v, %v, want nil", got, err)
+			}
+
+			if !repo.PingDB(ctx) {
+				t.Errorf("PingDB() = false, want true")
+			}
+		})
+	}
+}
+
+func TestAgentRepository_Conformance(t *testing.T) {
+	for _, b := range backends() {
+		t.Run(b.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := b.open(t).AgentRepo
+
+			seen := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+			touches := []model.Agent{
+				{ID: "b", LastSeen: seen, ReportInterval: 30},
+				{ID: "a", LastSeen: seen},
+				{ID: "b", LastSeen: seen.Add(-time.Minute)},
+				{ID: "a", LastSeen: seen.Add(time.Minute), ReportInterval: 5},
+			}
+			for i := range touches {
+				if err := repo.Touch(ctx, &touches[i]); err != nil {
+					t.Fatalf("Touch() error = %v", err)
+				}
+			}
+
+			agents, err := repo.ReadAll(ctx)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+
+			want := []model.Agent{
+				{ID: "a", LastSeen: seen.Add(time.Minute), ReportInterval: 5},
+				{ID: "b", LastSeen: seen, ReportInterval: 30},
+			}
+			if len(*agents) != len(want) {
+				t.Fatalf("ReadAll() = %+v, want %+v", *agents, want)
+			}
+			for i, got := range *agents {
+				if got.ID != want[i].ID || !got.LastSeen.Equal(want[i].LastSeen) ||
+					got.ReportInterval != want[i].ReportInterval {
+					t.Errorf("ReadAll()[%d] = %+v, want %+v", i, got, want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/dialect/sqlite3"
+)
+
+// sqliteDriver - the name of the SQLite database driver.
+const sqliteDriver = "sqlite"
+
+// sqliteDialect - the name of the query dialect for SQLite databases.
+const sqliteDialect = "metrix-sqlite"
+
+// sqliteTimeFormat - the fixed width format of timestamps stored by SQLite as text,
+// so the comparison of the stored values follows the time order.
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000000-07:00"
+
+func init() {
+	opts := sqlite3.DialectOptions()
+	// RETURNING is supported since SQLite 3.35.
+	opts.SupportsReturn = true
+	opts.TimeFormat = sqliteTimeFormat
+
+	goqu.RegisterDialect(sqliteDialect, opts)
+}
+
+// dialect - the method that returns the query builder matching the database driver.
+func (r *Group) dialect() goqu.DialectWrapper {
+	if r.DB != nil && r.DB.DriverName() == sqliteDriver {
+		return goqu.Dialect(sqliteDialect)
+	}
+
+	return goqu.Dialect("default")
+}
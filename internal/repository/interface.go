@@ -2,31 +2,44 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"metrix/internal/model"
 	"metrix/internal/storages"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
 )
 
 // MetricRepository - the interface that describes all metric repository methods.
+// Metrics are addressed by the series key, see model.SeriesKey.
 type MetricRepository interface {
 	Create(ctx context.Context, metric *model.Metric) (*model.Metric, error)
-	Read(ctx context.Context, metricID string) (*model.Metric, error)
+	Read(ctx context.Context, metricKey string) (*model.Metric, error)
 	ReadIDs(ctx context.Context) (*[]string, error)
-	ReadMany(ctx context.Context, metricIDs []string) (*[]model.Metric, error)
+	ReadMany(ctx context.Context, metricKeys []string) (*[]model.Metric, error)
 	Update(ctx context.Context, metric *model.Metric) (*model.Metric, error)
 	UpsertMany(ctx context.Context, metrics []model.Metric) (bool, error)
-	Delete(ctx context.Context, metricID string) error
+	Delete(ctx context.Context, metricKey string) error
+	ReadRange(ctx context.Context, metricKey string, from time.Time, to time.Time) (*[]model.Sample, error)
 	PingDB(ctx context.Context) bool
 }
 
+// AgentRepository - the interface that describes all agent repository methods.
+type AgentRepository interface {
+	Touch(ctx context.Context, agent *model.Agent) error
+	ReadAll(ctx context.Context) (*[]model.Agent, error)
+}
+
 // Group - the structure that stores all necessary repositories.
 type Group struct {
 	DB *sqlx.DB
+	KV *bolt.DB
 
 	MetricRepo MetricRepository
+	AgentRepo  AgentRepository
+	Updates    *BroadcastRepository
 }
 
 // PingDB - the method to pind database.
@@ -37,22 +50,39 @@ func (r *Group) PingDB(ctx context.Context) error {
 	return nil
 }
 
-// NewGroup - the builder function for Group structure.
+// NewGroup - the builder function for Group structure. The database takes precedence
+// over the embedded key-value store, without both metrics are kept in memory.
 func NewGroup(
 	ctx context.Context,
 	db *sqlx.DB,
+	kv *bolt.DB,
 	filePath string,
 	storeInterval int64,
 	restore bool,
+	retention time.Duration,
+	snapshots int,
 ) *Group {
 	group := &Group{}
 
 	if db != nil {
 		group.DB = db
-		group.MetricRepo = NewMetricRepository(group)
+		metricRepo := NewMetricRepository(group, retention)
+		go metricRepo.PeriodicCleanup(ctx)
+		group.MetricRepo = metricRepo
+		group.AgentRepo = NewAgentRepository(group)
+	} else if kv != nil {
+		group.KV = kv
+		metricRepo := NewBoltMetricRepository(group, retention)
+		go metricRepo.PeriodicCleanup(ctx)
+		group.MetricRepo = metricRepo
+		group.AgentRepo = NewBoltAgentRepository(group)
 	} else {
-		group.MetricRepo = storages.NewInMemoryStorage(ctx, filePath, storeInterval, restore)
+		group.MetricRepo = storages.NewInMemoryStorage(ctx, filePath, storeInterval, restore, retention, snapshots)
+		group.AgentRepo = storages.NewAgentMemoryStorage()
 	}
 
+	group.Updates = NewBroadcastRepository(group.MetricRepo)
+	group.MetricRepo = group.Updates
+
 	return group
 }
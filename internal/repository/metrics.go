@@ -5,24 +5,46 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"metrix/internal/model"
+	"metrix/pkg/logger"
 
 	"github.com/doug-martin/goqu/v9"
+	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 )
 
-const metricTName = "mtr_metrics"
+const (
+	metricTName = "mtr_metrics"
+	sampleTName = "mtr_samples"
+)
+
+// metricRow - the structure of the metrics table row, the series key is stored next to
+// the metric as it identifies the row together with the metric type.
+type metricRow struct {
+	model.Metric
+	SKey string `db:"skey" goqu:"skipupdate"`
+}
+
+// sampleRow - the structure of the samples table row.
+type sampleRow struct {
+	model.Sample
+	SKey string `db:"skey"`
+}
 
 // MetricRepositoryImpl - the structure for implementation of the MetricRepository concept.
 type MetricRepositoryImpl struct {
-	gr *Group
+	gr        *Group
+	retention time.Duration
 }
 
 // NewMetricRepository - the builder function for MetricRepositoryImpl.
-func NewMetricRepository(db *Group) *MetricRepositoryImpl {
+// The history of metric values is kept for the retention period, zero disables it.
+func NewMetricRepository(db *Group, retention time.Duration) *MetricRepositoryImpl {
 	return &MetricRepositoryImpl{
-		gr: db,
+		gr:        db,
+		retention: retention,
 	}
 }
 
@@ -31,10 +53,10 @@ func (r *MetricRepositoryImpl) Create(
 	ctx context.Context,
 	metric *model.Metric,
 ) (*model.Metric, error) {
-	qu, _, err := goqu.
+	qu, _, err := r.gr.dialect().
 		Insert(metricTName).
-		Rows(metric).
-		Returning("id", "mtype", "delta", "value").
+		Rows(metricRow{Metric: *metric, SKey: metric.SeriesKey()}).
+		Returning("id", "mtype", "labels", "delta", "value", "histogram", "summary").
 		ToSQL()
 	if err != nil {
 		return nil, errors.Wrapf(err, "create metric error during query building")
@@ -45,18 +67,32 @@ func (r *MetricRepositoryImpl) Create(
 		return nil, fmt.Errorf("failed to run insert: %w", err)
 	}
 
+	if err := r.insertSamples(ctx, r.gr.DB, []model.Metric{*metric}); err != nil {
+		return nil, err
+	}
+
 	return metric, nil
 }
 
-// Read - the method to read metric record from database.
+// Read - the method to read metric record from database by the series key.
 func (r *MetricRepositoryImpl) Read(
 	ctx context.Context,
-	metricID string,
+	metricKey string,
+) (*model.Metric, error) {
+	return r.read(ctx, r.gr.DB, metricKey)
+}
+
+// read - the method to read metric record with the given queryer, so the record may be
+// read within the transaction that changes it.
+func (r *MetricRepositoryImpl) read(
+	ctx context.Context,
+	queryer sqlx.QueryerContext,
+	metricKey string,
 ) (*model.Metric, error) {
-	qu, _, err := goqu.
+	qu, _, err := r.gr.dialect().
 		Select(&model.Metric{}).
 		From(metricTName).
-		Where(goqu.Ex{"id": metricID}).
+		Where(goqu.Ex{"skey": metricKey}).
 		Limit(1).
 		ToSQL()
 	if err != nil {
@@ -64,23 +100,23 @@ func (r *MetricRepositoryImpl) Read(
 	}
 
 	var newMetric model.Metric
-	row := r.gr.DB.QueryRowxContext(ctx, qu)
+	row := queryer.QueryRowxContext(ctx, qu)
 	err = row.StructScan(&newMetric)
-	if err != nil && errors.Is(err, sql.ErrNoRows) {
-		return nil, fmt.Errorf("read metric error during scan row: %w", err)
-	}
-
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 
+	if err != nil {
+		return nil, fmt.Errorf("read metric error during scan row: %w", err)
+	}
+
 	return &newMetric, nil
 }
 
-// ReadIDs - the method that retrieves metrics ids.
+// ReadIDs - the method that retrieves metrics series keys.
 func (r *MetricRepositoryImpl) ReadIDs(ctx context.Context) (*[]string, error) {
-	qu, _, err := goqu.
-		Select("id").
+	qu, _, err := r.gr.dialect().
+		Select("skey").
 		From(metricTName).
 		ToSQL()
 	if err != nil {
@@ -114,20 +150,20 @@ func (r *MetricRepositoryImpl) ReadIDs(ctx context.Context) (*[]string, error) {
 	return &ids, nil
 }
 
-// ReadMany - the method to read metrics in batch.
+// ReadMany - the method to read metrics in batch by the series keys.
 func (r *MetricRepositoryImpl) ReadMany(
 	ctx context.Context,
-	metricIDs []string,
+	metricKeys []string,
 ) (*[]model.Metric, error) {
-	inIDs := []any{}
-	for _, id := range metricIDs {
-		inIDs = append(inIDs, id)
+	inKeys := []any{}
+	for _, key := range metricKeys {
+		inKeys = append(inKeys, key)
 	}
 
-	qu, _, err := goqu.
+	qu, _, err := r.gr.dialect().
 		Select(&model.Metric{}).
 		From(metricTName).
-		Where(goqu.C("id").In(inIDs...)).
+		Where(goqu.C("skey").In(inKeys...)).
 		ToSQL()
 	if err != nil {
 		return nil, fmt.Errorf("read metrics error during query building: %w", err)
@@ -165,10 +201,10 @@ func (r *MetricRepositoryImpl) Update(
 	ctx context.Context,
 	metric *model.Metric,
 ) (*model.Metric, error) {
-	qu, _, err := goqu.
+	qu, _, err := r.gr.dialect().
 		Update(metricTName).
 		Set(metric).
-		Where(goqu.Ex{"id": metric.ID}).
+		Where(goqu.Ex{"skey": metric.SeriesKey()}).
 		ToSQL()
 	if err != nil {
 		return nil, fmt.Errorf("create metric error during query building: %w", err)
@@ -188,7 +224,11 @@ func (r *MetricRepositoryImpl) Update(
 		return nil, fmt.Errorf("update metric error during execute query: %w", err)
 	}
 
-	metricOut, err := r.Read(ctx, metric.ID)
+	if err := r.insertSamples(ctx, tx, []model.Metric{*metric}); err != nil {
+		return nil, err
+	}
+
+	metricOut, err := r.read(ctx, tx, metric.SeriesKey())
 	if err != nil {
 		return nil, errors.Wrapf(err, "refresh metric error")
 	}
@@ -218,18 +258,24 @@ func (r *MetricRepositoryImpl) UpsertMany(
 
 	rowsIn := []any{}
 	for _, m := range metrics {
-		rowsIn = append(rowsIn, m)
+		rowsIn = append(rowsIn, metricRow{Metric: m, SKey: m.SeriesKey()})
 	}
-	qu, _, err := goqu.Insert(metricTName).Rows(rowsIn...).ToSQL()
+	qu, _, err := r.gr.dialect().Insert(metricTName).Rows(rowsIn...).ToSQL()
 	if err != nil {
 		return false, fmt.Errorf("upsert metric error during query building: %w", err)
 	}
-	qu += " ON CONFLICT ON CONSTRAINT mtr_metrics_pk DO UPDATE SET delta = excluded.delta, value = excluded.value"
+	qu += " ON CONFLICT (skey, mtype) DO UPDATE SET" +
+		" delta = excluded.delta, value = excluded.value," +
+		" histogram = excluded.histogram, summary = excluded.summary"
 
 	if _, err := tx.ExecContext(ctx, qu); err != nil {
 		return false, fmt.Errorf("failed to upsert metric: %w", err)
 	}
 
+	if err := r.insertSamples(ctx, tx, metrics); err != nil {
+		return false, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return false, errors.Wrapf(err, "failed to commit")
 	}
@@ -237,14 +283,14 @@ func (r *MetricRepositoryImpl) UpsertMany(
 	return true, nil
 }
 
-// Delete - the method to remove records from the database.
+// Delete - the method to remove records from the database by the series key.
 func (r *MetricRepositoryImpl) Delete(
 	ctx context.Context,
-	metricID string,
+	metricKey string,
 ) error {
-	qu, _, err := goqu.
+	qu, _, err := r.gr.dialect().
 		Delete(metricTName).
-		Where(goqu.Ex{"id": metricID}).
+		Where(goqu.Ex{"skey": metricKey}).
 		ToSQL()
 	if err != nil {
 		return fmt.Errorf("delete metric error during query building: %w", err)
@@ -257,6 +303,124 @@ func (r *MetricRepositoryImpl) Delete(
 	return nil
 }
 
+// ReadRange - the method to read the metric series history within the time range.
+func (r *MetricRepositoryImpl) ReadRange(
+	ctx context.Context,
+	metricKey string,
+	from time.Time,
+	to time.Time,
+) (*[]model.Sample, error) {
+	qu, _, err := r.gr.dialect().
+		Select(&model.Sample{}).
+		From(sampleTName).
+		Where(
+			goqu.C("skey").Eq(metricKey),
+			goqu.C("ts").Gte(from),
+			goqu.C("ts").Lte(to),
+		).
+		Order(goqu.C("ts").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, fmt.Errorf("read range error during query building: %w", err)
+	}
+
+	rows, err := r.gr.DB.QueryxContext(ctx, qu)
+	if err != nil {
+		return nil, fmt.Errorf("read range error during querying: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			return
+		}
+	}()
+
+	samples := []model.Sample{}
+	for rows.Next() {
+		sample := model.Sample{}
+		if err := rows.StructScan(&sample); err != nil {
+			return nil, fmt.Errorf("read range error during scan rows: %w", err)
+		}
+		samples = append(samples, sample)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read range error during querying: %w", err)
+	}
+
+	return &samples, nil
+}
+
+// PeriodicCleanup - the method that periodically removes samples older than retention period.
+func (r *MetricRepositoryImpl) PeriodicCleanup(ctx context.Context) {
+	periodicCleanup(ctx, r.retention, r.cleanup)
+}
+
+// periodicCleanup - the function that runs the history cleanup until the context is done.
+func periodicCleanup(ctx context.Context, retention time.Duration, cleanup func(ctx context.Context) error) {
+	if retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(min(retention, time.Minute))
+	for {
+		select {
+		case <-ticker.C:
+			if err := cleanup(ctx); err != nil {
+				logger.Warn(ctx, fmt.Sprintf("failed to clean up history %s", err))
+			}
+		case <-ctx.Done():
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+func (r *MetricRepositoryImpl) cleanup(ctx context.Context) error {
+	qu, _, err := r.gr.dialect().
+		Delete(sampleTName).
+		Where(goqu.C("ts").Lt(time.Now().UTC().Add(-r.retention))).
+		ToSQL()
+	if err != nil {
+		return fmt.Errorf("cleanup error during query building: %w", err)
+	}
+
+	if _, err := r.gr.DB.ExecContext(ctx, qu); err != nil {
+		return fmt.Errorf("cleanup error during execute query: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MetricRepositoryImpl) insertSamples(
+	ctx context.Context,
+	execer sqlx.ExecerContext,
+	metrics []model.Metric,
+) error {
+	if r.retention <= 0 || len(metrics) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	rowsIn := []any{}
+	for i := range metrics {
+		rowsIn = append(rowsIn, sampleRow{
+			Sample: model.NewSample(&metrics[i], now),
+			SKey:   metrics[i].SeriesKey(),
+		})
+	}
+
+	qu, _, err := r.gr.dialect().Insert(sampleTName).Rows(rowsIn...).ToSQL()
+	if err != nil {
+		return fmt.Errorf("insert samples error during query building: %w", err)
+	}
+
+	if _, err := execer.ExecContext(ctx, qu); err != nil {
+		return fmt.Errorf("failed to insert samples: %w", err)
+	}
+
+	return nil
+}
+
 // PingDB - the method to ping database connection.
 func (r *MetricRepositoryImpl) PingDB(ctx context.Context) bool {
 	err := r.gr.PingDB(ctx)
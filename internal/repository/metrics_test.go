@@ -6,6 +6,7 @@ import (
 	"metrix/internal/model"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
@@ -19,7 +20,7 @@ func TestMetricRepositoryImpl_Create(t *testing.T) {
 	}
 
 	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
-	gr := NewGroup(ctx, sqlxDB, "", 0, false)
+	gr := NewGroup(ctx, sqlxDB, nil, "", 0, false, 0, 1)
 
 	rows := mock.
 		NewRows([]string{"id", "mtype", "delta", "value"}).
@@ -84,7 +85,7 @@ func TestMetricRepositoryImpl_Read(t *testing.T) {
 	}
 
 	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
-	gr := NewGroup(ctx, sqlxDB, "", 0, false)
+	gr := NewGroup(ctx, sqlxDB, nil, "", 0, false, 0, 1)
 
 	rows := mock.
 		NewRows([]string{"id", "mtype", "delta", "value"}).
@@ -145,7 +146,7 @@ func TestMetricRepositoryImpl_ReadIDs(t *testing.T) {
 	}
 
 	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
-	gr := NewGroup(ctx, sqlxDB, "", 0, false)
+	gr := NewGroup(ctx, sqlxDB, nil, "", 0, false, 0, 1)
 
 	rows := mock.
 		NewRows([]string{"id"}).
@@ -198,7 +199,7 @@ func TestMetricRepositoryImpl_ReadMany(t *testing.T) {
 	}
 
 	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
-	gr := NewGroup(ctx, sqlxDB, "", 0, false)
+	gr := NewGroup(ctx, sqlxDB, nil, "", 0, false, 0, 1)
 
 	rows := mock.
 		NewRows([]string{"id", "mtype", "delta", "value"}).
@@ -261,7 +262,7 @@ func TestMetricRepositoryImpl_Update(t *testing.T) {
 	}
 
 	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
-	gr := NewGroup(ctx, sqlxDB, "", 0, false)
+	gr := NewGroup(ctx, sqlxDB, nil, "", 0, false, 0, 1)
 
 	mock.ExpectBegin()
 	mock.ExpectExec(`UPDATE +.?`).WillReturnResult(
@@ -330,7 +331,7 @@ func TestMetricRepositoryImpl_UpsertMany(t *testing.T) {
 	}
 
 	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
-	gr := NewGroup(ctx, sqlxDB, "", 0, false)
+	gr := NewGroup(ctx, sqlxDB, nil, "", 0, false, 0, 1)
 
 	mock.ExpectBegin()
 	mock.ExpectExec(`INSERT +.?`).WillReturnResult(
@@ -392,7 +393,7 @@ func TestMetricRepositoryImpl_Delete(t *testing.T) {
 	}
 
 	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
-	gr := NewGroup(ctx, sqlxDB, "", 0, false)
+	gr := NewGroup(ctx, sqlxDB, nil, "", 0, false, 0, 1)
 
 	mock.ExpectExec(`DELETE +.?`).WillReturnResult(
 		sqlmock.NewResult(1, 1),
@@ -428,3 +429,108 @@ func TestMetricRepositoryImpl_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricRepositoryImpl_UpsertManyWithHistory(t *testing.T) {
+	ctx := context.Background()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.FailNow()
+	}
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	gr := NewGroup(ctx, sqlxDB, nil, "", 0, false, 0, 1)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "mtr_metrics" +.?`).WillReturnResult(
+		sqlmock.NewResult(1, 1),
+	)
+	mock.ExpectExec(`INSERT INTO "mtr_samples" +.?`).WillReturnResult(
+		sqlmock.NewResult(1, 1),
+	)
+	mock.ExpectCommit()
+
+	r := NewMetricRepository(gr, time.Hour)
+	got, err := r.UpsertMany(ctx, []model.Metric{
+		{
+			ID:    "Metric 1",
+			MType: "gauge",
+			Value: func() *float64 { i := float64(300); return &i }(),
+		},
+	})
+	if err != nil || !got {
+		t.Errorf("MetricRepositoryImpl.UpsertMany() = %v, error = %v", got, err)
+		return
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("MetricRepositoryImpl.UpsertMany() expectations: %v", err)
+	}
+}
+
+func TestMetricRepositoryImpl_ReadRange(t *testing.T) {
+	ctx := context.Background()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.FailNow()
+	}
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	gr := NewGroup(ctx, sqlxDB, nil, "", 0, false, 0, 1)
+
+	ts := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	rows := mock.
+		NewRows([]string{"id", "mtype", "ts", "delta", "value"}).
+		AddRow("Metric 1", "gauge", ts, nil, func() *float64 { i := float64(300); return &i }())
+
+	mock.ExpectQuery(`SELECT .+ FROM "mtr_samples"`).WillReturnRows(rows)
+
+	type fields struct {
+		gr *Group
+	}
+	type args struct {
+		metricID string
+		from     time.Time
+		to       time.Time
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		want    *[]model.Sample
+		wantErr bool
+	}{
+		{
+			name:   "Test 1: Success read range",
+			fields: fields{gr: gr},
+			args: args{
+				metricID: "Metric 1",
+				from:     ts.Add(-time.Hour),
+				to:       ts,
+			},
+			want: &[]model.Sample{
+				{
+					ID:        "Metric 1",
+					MType:     "gauge",
+					Timestamp: ts,
+					Value:     func() *float64 { i := float64(300); return &i }(),
+				},
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &MetricRepositoryImpl{
+				gr: tt.fields.gr,
+			}
+			got, err := r.ReadRange(ctx, tt.args.metricID, tt.args.from, tt.args.to)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MetricRepositoryImpl.ReadRange() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MetricRepositoryImpl.ReadRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package storages
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"metrix/internal/model"
+)
+
+// AgentMemoryStorage - is the structure to keep known agents in memory.
+type AgentMemoryStorage struct {
+	mux    *sync.RWMutex
+	agents map[string]model.Agent
+}
+
+// NewAgentMemoryStorage - the building function for AgentMemoryStorage.
+func NewAgentMemoryStorage() *AgentMemoryStorage {
+	return &AgentMemoryStorage{
+		mux:    &sync.RWMutex{},
+		agents: make(map[string]model.Agent),
+	}
+}
+
+// Touch - the method to register the agent or refresh its last seen time and declared
+// report interval, an undeclared interval keeps the stored one.
+func (s *AgentMemoryStorage) Touch(
+	ctx context.Context,
+	agent *model.Agent,
+) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	stored, ok := s.agents[agent.ID]
+	if !ok {
+		stored = model.Agent{ID: agent.ID}
+	}
+
+	if agent.LastSeen.After(stored.LastSeen) {
+		stored.LastSeen = agent.LastSeen
+	}
+	if agent.ReportInterval > 0 {
+		stored.ReportInterval = agent.ReportInterval
+	}
+	s.agents[agent.ID] = stored
+
+	return nil
+}
+
+// ReadAll - the method to read all known agents ordered by ID.
+func (s *AgentMemoryStorage) ReadAll(ctx context.Context) (*[]model.Agent, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	agents := make([]model.Agent, 0, len(s.agents))
+	for _, agent := range s.agents {
+		agents = append(agents, agent)
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+
+	return &agents, nil
+}
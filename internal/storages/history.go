@@ -0,0 +1,46 @@
+package storages
+
+import (
+	"time"
+
+	"metrix/internal/model"
+)
+
+// historyCapacity - the maximum number of samples kept per metric in memory.
+const historyCapacity = 4096
+
+// ring - the bounded circular buffer of metric samples ordered by time,
+// it grows up to its capacity and then overwrites the oldest samples.
+type ring struct {
+	samples  []model.Sample
+	capacity int
+	head     int
+}
+
+func newRing(capacity int) *ring {
+	return &ring{capacity: capacity}
+}
+
+// push - adds a sample overwriting the oldest one when the buffer is full.
+func (r *ring) push(s model.Sample) {
+	if len(r.samples) < r.capacity {
+		r.samples = append(r.samples, s)
+		return
+	}
+	r.samples[r.head] = s
+	r.head = (r.head + 1) % r.capacity
+}
+
+// between - returns samples with timestamps within [from, to].
+func (r *ring) between(from, to time.Time) []model.Sample {
+	out := []model.Sample{}
+	for i := range r.samples {
+		s := r.samples[(r.head+i)%len(r.samples)]
+		if s.Timestamp.Before(from) || s.Timestamp.After(to) {
+			continue
+		}
+		out = append(out, s)
+	}
+
+	return out
+}
@@ -4,9 +4,9 @@ package storages
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
-	"os"
 	"sync"
 	"time"
 
@@ -14,13 +14,21 @@ import (
 	"metrix/pkg/logger"
 )
 
-// MemoryStorage - is the structure to manage inmemory storage.
+// defaultCompactInterval - the compaction interval used when store interval is zero.
+const defaultCompactInterval = 5 * time.Minute
+
+// MemoryStorage - is the structure to manage inmemory storage. Series are spread over
+// hashed shards with their own locks. With a file path every change is made durable in the
+// write-ahead log before it is applied to memory, the log is periodically compacted into the
+// snapshot file without blocking writers.
 type MemoryStorage struct {
-	mux           *sync.RWMutex
-	storage       map[string]model.Metric
-	saveSync      bool
-	storeInterval int64
-	filePath      string
+	shards          []*shard
+	compactMux      sync.Mutex
+	retention       time.Duration
+	compactInterval time.Duration
+	filePath        string
+	snapshots       int
+	wal             *wal
 }
 
 // Create - the method to add metric record to memory storage.
@@ -28,16 +36,8 @@ func (s *MemoryStorage) Create(
 	ctx context.Context,
 	metric *model.Metric,
 ) (*model.Metric, error) {
-	s.mux.Lock()
-	defer s.mux.Unlock()
-
-	s.storage[metric.ID] = *metric
-
-	if s.saveSync {
-		err := s.writeToFile()
-		if err != nil {
-			return nil, fmt.Errorf("failed to backup storage: %w", err)
-		}
+	if err := s.apply(walEntry{Op: walUpsert, Metrics: []model.Metric{*metric}}); err != nil {
+		return nil, fmt.Errorf("failed to backup storage: %w", err)
 	}
 
 	return metric, nil
@@ -46,12 +46,13 @@ func (s *MemoryStorage) Create(
 // Read - the method to read a metric record from memory storage.
 func (s *MemoryStorage) Read(
 	ctx context.Context,
-	metricID string,
+	metricKey string,
 ) (*model.Metric, error) {
-	s.mux.RLock()
-	defer s.mux.RUnlock()
+	sh := s.shardFor(metricKey)
+	sh.mux.RLock()
+	defer sh.mux.RUnlock()
 
-	m, ok := s.storage[metricID]
+	m, ok := sh.storage[metricKey]
 	if !ok {
 		return nil, nil
 	}
@@ -59,17 +60,18 @@ func (s *MemoryStorage) Read(
 	return &m, nil
 }
 
-// ReadIDs - the method to read stored metrics IDs.
+// ReadIDs - the method to read stored metrics series keys.
 func (s *MemoryStorage) ReadIDs(
 	ctx context.Context,
 ) (*[]string, error) {
-	s.mux.RLock()
-	defer s.mux.RUnlock()
-
 	var ids []string
 
-	for k := range s.storage {
-		ids = append(ids, k)
+	for _, sh := range s.shards {
+		sh.mux.RLock()
+		for k := range sh.storage {
+			ids = append(ids, k)
+		}
+		sh.mux.RUnlock()
 	}
 
 	return &ids, nil
@@ -80,16 +82,8 @@ func (s *MemoryStorage) Update(
 	ctx context.Context,
 	metric *model.Metric,
 ) (*model.Metric, error) {
-	s.mux.Lock()
-	defer s.mux.Unlock()
-
-	s.storage[metric.ID] = *metric
-
-	if s.saveSync {
-		err := s.writeToFile()
-		if err != nil {
-			return nil, fmt.Errorf("failed to backup storage: %w", err)
-		}
+	if err := s.apply(walEntry{Op: walUpsert, Metrics: []model.Metric{*metric}}); err != nil {
+		return nil, fmt.Errorf("failed to backup storage: %w", err)
 	}
 
 	return metric, nil
@@ -98,137 +92,269 @@ func (s *MemoryStorage) Update(
 // Delete - the method to delete stored metrics.
 func (s *MemoryStorage) Delete(
 	ctx context.Context,
-	metricID string,
+	metricKey string,
 ) error {
-	s.mux.Lock()
-	defer s.mux.Unlock()
+	if err := s.apply(walEntry{Op: walDelete, Key: metricKey}); err != nil {
+		return fmt.Errorf("failed to backup storage: %w", err)
+	}
 
-	delete(s.storage, metricID)
+	return nil
+}
 
-	if s.saveSync {
-		err := s.writeToFile()
-		if err != nil {
-			return fmt.Errorf("failed to backup storage: %w", err)
-		}
+// apply - the method that logs the change and applies it to memory under the locks of
+// the touched shards, so the log order matches the memory state of every series.
+// The change becomes visible only once it is durable, a failed change is not applied.
+func (s *MemoryStorage) apply(entry walEntry) error {
+	unlock := s.lockEntry(entry)
+	defer unlock()
+
+	seq, err := s.wal.append(entry)
+	if err != nil {
+		return err
 	}
+	if err := s.wal.commit(seq); err != nil {
+		return err
+	}
+	s.applyEntry(entry, time.Now().UTC())
 
 	return nil
 }
 
+// applyEntry - the method that applies the logged change to memory, the touched shards
+// must be locked. History is recorded unless ts is zero, which is the case for the
+// replayed changes.
+func (s *MemoryStorage) applyEntry(entry walEntry, ts time.Time) {
+	switch entry.Op {
+	case walUpsert:
+		for _, m := range entry.Metrics {
+			sh := s.shardFor(m.SeriesKey())
+			sh.storage[m.SeriesKey()] = m
+			if !ts.IsZero() {
+				s.record(sh, &m, ts)
+			}
+		}
+	case walDelete:
+		sh := s.shardFor(entry.Key)
+		delete(sh.storage, entry.Key)
+		delete(sh.history, entry.Key)
+	}
+}
+
 // NewInMemmoryStorage - the building function for InMemoryStorage.
+// The history of metric values is kept for the retention period, zero disables it.
+// With a file path the storage is restored from the snapshot and the write-ahead log
+// when restore is set, otherwise it starts empty and the log is cleared. Up to snapshots
+// generations of the snapshot are kept, at least one.
 func NewInMemoryStorage(
 	ctx context.Context,
 	filePath string,
 	storeInterval int64,
 	restore bool,
+	retention time.Duration,
+	snapshots int,
 ) *MemoryStorage {
-	saveSync := false
-	if storeInterval == 0 {
-		saveSync = true
+	ms := newMemoryStorage(ctx, defaultShards, filePath, storeInterval, restore, retention, snapshots)
+	if filePath != "" {
+		go ms.PeriodicBackup(ctx)
+	}
+
+	return ms
+}
+
+// newMemoryStorage - the function that builds and restores the storage with the given
+// number of shards, the periodic backup is not started.
+func newMemoryStorage(
+	ctx context.Context,
+	shards int,
+	filePath string,
+	storeInterval int64,
+	restore bool,
+	retention time.Duration,
+	snapshots int,
+) *MemoryStorage {
+	compactInterval := time.Duration(storeInterval) * time.Second
+	if compactInterval <= 0 {
+		compactInterval = defaultCompactInterval
 	}
 
 	ms := &MemoryStorage{
-		mux:           &sync.RWMutex{},
-		storage:       make(map[string]model.Metric),
-		storeInterval: storeInterval,
-		saveSync:      saveSync,
-		filePath:      filePath,
+		shards:          newShards(shards),
+		retention:       retention,
+		compactInterval: compactInterval,
+		filePath:        filePath,
+		snapshots:       max(snapshots, 1),
 	}
 
-	if restore {
-		ms.restore()
+	if filePath == "" {
+		return ms
 	}
 
-	if !saveSync {
-		go ms.PeriodicBackup(ctx)
+	// without the log changes are still saved by the periodic snapshots
+	w, err := openWAL(filePath + walFileSuffix)
+	if err != nil {
+		logger.Error(ctx, "failed to open wal, changes since the last snapshot may be lost", err)
+	} else {
+		ms.wal = w
+	}
+
+	if restore {
+		if err := ms.restore(ctx); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.Warn(ctx, fmt.Sprintf("failed to restore snapshot: %s", err))
+		}
+
+		replayed, err := ms.wal.replay(func(entry walEntry) {
+			ms.applyEntry(entry, time.Time{})
+		})
+		if err != nil {
+			logger.Warn(ctx, fmt.Sprintf("failed to replay wal: %s", err))
+		}
+		logger.Info(ctx, fmt.Sprintf("restored %d metrics, %d wal records replayed", ms.count(), replayed))
+	} else if err := ms.wal.reset(); err != nil {
+		logger.Warn(ctx, fmt.Sprintf("failed to reset wal: %s", err))
 	}
 
 	return ms
 }
 
-// PeriodicBackup - the method that initiates periodic inmemory backups.
+// PeriodicBackup - the method that initiates periodic compaction of the write-ahead log
+// into the snapshot, the log is compacted and closed once the context is done.
 func (s *MemoryStorage) PeriodicBackup(ctx context.Context) {
-	ticker := time.NewTicker(time.Duration(int64(time.Second) * s.storeInterval))
+	ticker := time.NewTicker(s.compactInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-ticker.C:
-			err := s.muxWriteToFile()
-			if err != nil {
+			if err := s.compact(); err != nil {
 				logger.Warn(ctx, fmt.Sprintf("failed to backup db %s", err))
 			}
 		case <-ctx.Done():
-			err := s.muxWriteToFile()
-			if err != nil {
+			if err := s.compact(); err != nil {
 				logger.Warn(ctx, fmt.Sprintf("failed to backup db %s", err))
 			}
-			ticker.Stop()
+			if err := s.wal.close(); err != nil {
+				logger.Warn(ctx, fmt.Sprintf("failed to close wal %s", err))
+			}
 			return
 		}
 	}
 }
 
-func (s *MemoryStorage) muxWriteToFile() error {
-	s.mux.RLock()
-	defer s.mux.RUnlock()
+// compact - the method that writes the durable snapshot and drops the compacted part of
+// the write-ahead log. The log is rotated first and every shard is copied afterwards, so
+// the snapshot covers each rotated record. Writers are blocked only while their shard is
+// copied, records landing in the new log meanwhile are safe to replay over the snapshot.
+func (s *MemoryStorage) compact() error {
+	if s.filePath == "" {
+		return nil
+	}
 
-	err := s.writeToFile()
-	if err != nil {
+	s.compactMux.Lock()
+	defer s.compactMux.Unlock()
+
+	if err := s.wal.rotate(); err != nil {
+		return fmt.Errorf("failed to rotate wal: %w", err)
+	}
+
+	if err := s.writeToFile(); err != nil {
 		return fmt.Errorf("failed to back up: %w", err)
 	}
 
+	if err := s.wal.dropRotated(); err != nil {
+		return fmt.Errorf("failed to drop rotated wal: %w", err)
+	}
+
 	return nil
 }
 
 func (s *MemoryStorage) writeToFile() error {
-	if s.filePath == "" {
-		return nil
-	}
-
-	data, err := json.Marshal(s.storage)
+	data, err := json.Marshal(s.copyStorage())
 	if err != nil {
 		return fmt.Errorf("failed to marshal storage: %w", err)
 	}
 
-	err = os.WriteFile(s.filePath, data, fs.ModePerm)
-	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	if err := writeSnapshot(s.filePath, data, s.snapshots-1); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
 	}
 
 	return nil
 }
 
-func (s *MemoryStorage) restore() error {
+// copyStorage - the method that copies the series shard by shard.
+func (s *MemoryStorage) copyStorage() map[string]model.Metric {
+	storage := make(map[string]model.Metric)
+
+	for _, sh := range s.shards {
+		sh.mux.RLock()
+		for k, m := range sh.storage {
+			storage[k] = m
+		}
+		sh.mux.RUnlock()
+	}
+
+	return storage
+}
+
+// count - the method that returns the number of stored series.
+func (s *MemoryStorage) count() int {
+	n := 0
+
+	for _, sh := range s.shards {
+		sh.mux.RLock()
+		n += len(sh.storage)
+		sh.mux.RUnlock()
+	}
+
+	return n
+}
+
+// restore - the method that loads the latest valid snapshot into memory, falling back
+// to previous generations when the latest one is corrupted. Must be called before the
+// storage is shared.
+func (s *MemoryStorage) restore(ctx context.Context) error {
 	if s.filePath == "" {
 		return nil
 	}
 
-	s.mux.Lock()
-	defer s.mux.Unlock()
+	generation, err := readSnapshot(
+		s.filePath,
+		s.snapshots-1,
+		func(payload []byte) error {
+			newStorage := make(map[string]model.Metric)
+			if err := json.Unmarshal(payload, &newStorage); err != nil {
+				return fmt.Errorf("failed to unmarshal storage: %w", err)
+			}
 
-	data, err := os.ReadFile(s.filePath)
+			shards := newShards(len(s.shards))
+			for k, m := range newStorage {
+				shards[shardIndex(k, len(shards))].storage[k] = m
+			}
+			s.shards = shards
+			return nil
+		},
+		func(path string, err error) {
+			logger.Warn(ctx, fmt.Sprintf("skipping snapshot %s: %s", path, err))
+		},
+	)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return fmt.Errorf("failed to read snapshot: %w", err)
 	}
 
-	newStorage := make(map[string]model.Metric)
-	err = json.Unmarshal(data, &newStorage)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal storage: %w", err)
+	if generation > 0 {
+		logger.Warn(ctx, fmt.Sprintf("restored from previous snapshot %s", snapshotPath(s.filePath, generation)))
 	}
 
-	s.storage = newStorage
-
 	return nil
 }
 
 // ReadMany - the method to read many metrics records.
-func (s *MemoryStorage) ReadMany(ctx context.Context, metricIDs []string) (*[]model.Metric, error) {
-	s.mux.RLock()
-	defer s.mux.RUnlock()
-
+func (s *MemoryStorage) ReadMany(ctx context.Context, metricKeys []string) (*[]model.Metric, error) {
 	metrics := []model.Metric{}
-	for _, id := range metricIDs {
-		metric, ok := s.storage[id]
+	for _, key := range metricKeys {
+		sh := s.shardFor(key)
+		sh.mux.RLock()
+		metric, ok := sh.storage[key]
+		sh.mux.RUnlock()
 		if ok {
 			metrics = append(metrics, metric)
 		}
@@ -242,21 +368,51 @@ func (s *MemoryStorage) UpsertMany(
 	ctx context.Context,
 	metrics []model.Metric,
 ) (bool, error) {
-	s.mux.Lock()
-	defer s.mux.Unlock()
+	if err := s.apply(walEntry{Op: walUpsert, Metrics: metrics}); err != nil {
+		return false, fmt.Errorf("failed to backup storage: %w", err)
+	}
+
+	return true, nil
+}
+
+// ReadRange - the method to read the metric history within the time range.
+func (s *MemoryStorage) ReadRange(
+	ctx context.Context,
+	metricKey string,
+	from time.Time,
+	to time.Time,
+) (*[]model.Sample, error) {
+	sh := s.shardFor(metricKey)
+	sh.mux.RLock()
+	defer sh.mux.RUnlock()
 
-	for _, m := range metrics {
-		s.storage[m.ID] = m
+	samples := []model.Sample{}
+
+	r, ok := sh.history[metricKey]
+	if !ok {
+		return &samples, nil
 	}
 
-	if s.saveSync {
-		err := s.writeToFile()
-		if err != nil {
-			return false, fmt.Errorf("failed to backup storage: %w", err)
-		}
+	if cutoff := time.Now().UTC().Add(-s.retention); from.Before(cutoff) {
+		from = cutoff
 	}
+	samples = r.between(from, to)
 
-	return true, nil
+	return &samples, nil
+}
+
+// record - the method to append the metric state to its history, the shard must be locked.
+func (s *MemoryStorage) record(sh *shard, metric *model.Metric, ts time.Time) {
+	if s.retention <= 0 {
+		return
+	}
+
+	r, ok := sh.history[metric.SeriesKey()]
+	if !ok {
+		r = newRing(historyCapacity)
+		sh.history[metric.SeriesKey()] = r
+	}
+	r.push(model.NewSample(metric, ts))
 }
 
 // PingDB - the method to ping inmemory storage.
@@ -0,0 +1,74 @@
+package storages
+
+import (
+	"hash/fnv"
+	"slices"
+	"sync"
+
+	"metrix/internal/model"
+)
+
+// defaultShards - the number of shards of the memory storage, a power of two.
+const defaultShards = 64
+
+// shard - the part of the memory storage holding the series with the same key hash,
+// each shard has its own lock so writes to different series do not contend.
+type shard struct {
+	mux     sync.RWMutex
+	storage map[string]model.Metric
+	history map[string]*ring
+}
+
+// newShards - the builder function for n empty shards, n must be a power of two.
+func newShards(n int) []*shard {
+	shards := make([]*shard, n)
+	for i := range shards {
+		shards[i] = &shard{
+			storage: make(map[string]model.Metric),
+			history: make(map[string]*ring),
+		}
+	}
+
+	return shards
+}
+
+// shardIndex - the function that maps the series key to its shard.
+func shardIndex(key string, n int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	return int(h.Sum32() & uint32(n-1))
+}
+
+// shardFor - the method that returns the shard holding the series.
+func (s *MemoryStorage) shardFor(key string) *shard {
+	return s.shards[shardIndex(key, len(s.shards))]
+}
+
+// lockEntry - the method that write-locks every shard touched by the change in the
+// index order, so concurrent changes spanning several shards cannot deadlock.
+// The returned function releases the locks.
+func (s *MemoryStorage) lockEntry(entry walEntry) func() {
+	var indexes []int
+	switch entry.Op {
+	case walUpsert:
+		indexes = make([]int, 0, len(entry.Metrics))
+		for _, m := range entry.Metrics {
+			indexes = append(indexes, shardIndex(m.SeriesKey(), len(s.shards)))
+		}
+		slices.Sort(indexes)
+		indexes = slices.Compact(indexes)
+	case walDelete:
+		indexes = []int{shardIndex(entry.Key, len(s.shards))}
+	}
+
+	for _, i := range indexes {
+		s.shards[i].mux.Lock()
+	}
+
+	return func() {
+		for _, i := range indexes {
+			s.shards[i].mux.Unlock()
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package storages
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"metrix/internal/model"
+)
+
+func TestMemoryStorage_ConcurrentCompact(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	s := NewInMemoryStorage(ctx, path, 0, true, 0, 1)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				m := counter(fmt.Sprintf("Counter%d", w*50+i), int64(i))
+				if _, err := s.UpsertMany(ctx, []model.Metric{m, counter("Shared", int64(w))}); err != nil {
+					t.Errorf("UpsertMany() error = %v", err)
+				}
+			}
+		}(w)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := s.compact(); err != nil {
+			t.Fatalf("compact() error = %v", err)
+		}
+	}
+	wg.Wait()
+
+	want, _ := s.Read(ctx, "Shared")
+	restored := NewInMemoryStorage(ctx, path, 0, true, 0, 1)
+
+	if ids, _ := restored.ReadIDs(ctx); len(*ids) != 401 {
+		t.Errorf("restored %d series, want 401", len(*ids))
+	}
+	if got, _ := restored.Read(ctx, "Shared"); got == nil || *got.Delta != *want.Delta {
+		t.Errorf("restored Shared = %v, want %v", got, want)
+	}
+}
+
+func TestMemoryStorage_InterruptedCompact(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	s := NewInMemoryStorage(ctx, path, 0, true, 0, 1)
+
+	if _, err := s.UpsertMany(ctx, []model.Metric{counter("PollCount", 1)}); err != nil {
+		t.Fatalf("UpsertMany() error = %v", err)
+	}
+
+	// The process stops after the log is rotated, before the snapshot is written.
+	if err := s.wal.rotate(); err != nil {
+		t.Fatalf("rotate() error = %v", err)
+	}
+	if _, err := s.UpsertMany(ctx, []model.Metric{counter("Frees", 1)}); err != nil {
+		t.Fatalf("UpsertMany() error = %v", err)
+	}
+	if err := s.wal.rotate(); err != nil {
+		t.Fatalf("rotate() error = %v", err)
+	}
+	if _, err := s.UpsertMany(ctx, []model.Metric{counter("PollCount", 7)}); err != nil {
+		t.Fatalf("UpsertMany() error = %v", err)
+	}
+
+	restored := NewInMemoryStorage(ctx, path, 0, true, 0, 1)
+
+	tests := []struct {
+		name  string
+		key   string
+		delta int64
+	}{
+		{name: "Test 1", key: "PollCount", delta: 7},
+		{name: "Test 2", key: "Frees", delta: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := restored.Read(ctx, tt.key)
+			if m == nil || *m.Delta != tt.delta {
+				t.Errorf("Read(%s) = %v, want delta %d", tt.key, m, tt.delta)
+			}
+		})
+	}
+
+	if err := restored.compact(); err != nil {
+		t.Fatalf("compact() error = %v", err)
+	}
+	if _, err := os.Stat(path + walFileSuffix + walRotatedSuffix); !os.IsNotExist(err) {
+		t.Errorf("rotated wal must be removed after compaction, stat error = %v", err)
+	}
+}
+
+// newBenchStorage - the function that builds the storage with the given number of shards,
+// a single shard serializes every operation on one lock like the former storage.
+// The periodic backup of a file storage is stopped and awaited on cleanup.
+func newBenchStorage(b *testing.B, shards int, path string) *MemoryStorage {
+	b.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newMemoryStorage(ctx, shards, path, 0, false, time.Hour, 1)
+	if path == "" {
+		b.Cleanup(cancel)
+		return s
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.PeriodicBackup(ctx)
+		close(done)
+	}()
+	b.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return s
+}
+
+func benchmarkShards(b *testing.B, bench func(b *testing.B, shards int)) {
+	for _, shards := range []int{1, defaultShards} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			bench(b, shards)
+		})
+	}
+}
+
+func BenchmarkMemoryStorage_UpsertMany(b *testing.B) {
+	benchmarkShards(b, func(b *testing.B, shards int) {
+		s := newBenchStorage(b, shards, "")
+		ctx := context.Background()
+
+		var agents atomic.Int64
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			agent := agents.Add(1)
+			batch := make([]model.Metric, 10)
+			for i := range batch {
+				batch[i] = counter(fmt.Sprintf("Agent%dCounter%d", agent, i), 1)
+			}
+
+			for pb.Next() {
+				if _, err := s.UpsertMany(ctx, batch); err != nil {
+					b.Errorf("UpsertMany() error = %v", err)
+				}
+			}
+		})
+	})
+}
+
+func BenchmarkMemoryStorage_ReadWrite(b *testing.B) {
+	benchmarkShards(b, func(b *testing.B, shards int) {
+		s := newBenchStorage(b, shards, "")
+		ctx := context.Background()
+
+		keys := make([]string, 1000)
+		for i := range keys {
+			m := counter(fmt.Sprintf("Counter%d", i), 1)
+			keys[i] = m.SeriesKey()
+			if _, err := s.Create(ctx, &m); err != nil {
+				b.Fatalf("Create() error = %v", err)
+			}
+		}
+
+		var workers atomic.Int64
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			i := int(workers.Add(1)) * 7
+			for pb.Next() {
+				i++
+				key := keys[i%len(keys)]
+				if i%4 == 0 {
+					m := counter(key, int64(i))
+					if _, err := s.Update(ctx, &m); err != nil {
+						b.Errorf("Update() error = %v", err)
+					}
+					continue
+				}
+				if _, err := s.Read(ctx, key); err != nil {
+					b.Errorf("Read() error = %v", err)
+				}
+			}
+		})
+	})
+}
+
+func BenchmarkMemoryStorage_UpsertDuringCompact(b *testing.B) {
+	benchmarkShards(b, func(b *testing.B, shards int) {
+		s := newBenchStorage(b, shards, filepath.Join(b.TempDir(), "metrics.json"))
+		ctx := context.Background()
+
+		for i := 0; i < 10000; i++ {
+			m := counter(fmt.Sprintf("Counter%d", i), 1)
+			if _, err := s.Create(ctx, &m); err != nil {
+				b.Fatalf("Create() error = %v", err)
+			}
+		}
+
+		done := make(chan struct{})
+		compacted := make(chan struct{})
+		go func() {
+			defer close(compacted)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					if err := s.compact(); err != nil {
+						b.Errorf("compact() error = %v", err)
+						return
+					}
+				}
+			}
+		}()
+
+		var agents atomic.Int64
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			m := counter(fmt.Sprintf("Agent%d", agents.Add(1)), 1)
+			for pb.Next() {
+				if _, err := s.UpsertMany(ctx, []model.Metric{m}); err != nil {
+					b.Errorf("UpsertMany() error = %v", err)
+				}
+			}
+		})
+		b.StopTimer()
+
+		close(done)
+		<-compacted
+	})
+}
@@ -0,0 +1,198 @@
+package storages
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// snapshotMagic - the first word of the snapshot header.
+const snapshotMagic = "METRIX-SNAPSHOT"
+
+// snapshotVersion - the current version of the snapshot format. Version 0 is the
+// headerless json written by earlier releases, it is still accepted on restore.
+const snapshotVersion = 1
+
+// snapshotTmpSuffix - the suffix of the snapshot being written.
+const snapshotTmpSuffix = ".tmp"
+
+// errSnapshotCorrupted - the error returned for snapshots failing validation.
+var errSnapshotCorrupted = errors.New("snapshot is corrupted")
+
+// encodeSnapshot - the function that prepends the header line to the payload:
+// magic, format version, crc32 of the payload and its length.
+func encodeSnapshot(payload []byte) []byte {
+	header := fmt.Sprintf(
+		"%s %d %08x %d\n",
+		snapshotMagic,
+		snapshotVersion,
+		crc32.ChecksumIEEE(payload),
+		len(payload),
+	)
+
+	return append([]byte(header), payload...)
+}
+
+// decodeSnapshot - the function that validates the snapshot and returns its payload.
+func decodeSnapshot(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return data, nil
+	}
+
+	header, payload, ok := bytes.Cut(data, []byte("\n"))
+	if !ok {
+		return nil, fmt.Errorf("%w: missing header", errSnapshotCorrupted)
+	}
+
+	var (
+		magic    string
+		version  int
+		checksum uint32
+		length   int
+	)
+	if _, err := fmt.Sscanf(string(header), "%s %d %x %d", &magic, &version, &checksum, &length); err != nil {
+		return nil, fmt.Errorf("%w: invalid header: %w", errSnapshotCorrupted, err)
+	}
+
+	if magic != snapshotMagic {
+		return nil, fmt.Errorf("%w: unknown format %q", errSnapshotCorrupted, magic)
+	}
+	if version < 1 || version > snapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", errSnapshotCorrupted, version)
+	}
+	if len(payload) != length {
+		return nil, fmt.Errorf("%w: length %d, want %d", errSnapshotCorrupted, len(payload), length)
+	}
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return nil, fmt.Errorf("%w: checksum mismatch", errSnapshotCorrupted)
+	}
+
+	return payload, nil
+}
+
+// snapshotPath - the function that returns the path of the n-th previous snapshot,
+// zero is the latest one.
+func snapshotPath(path string, n int) string {
+	if n == 0 {
+		return path
+	}
+
+	return path + "." + strconv.Itoa(n)
+}
+
+// writeSnapshot - the function that durably writes the snapshot keeping up to previous
+// older generations. The new snapshot is synced to a temporary file and renamed over
+// the latest one, so the path always holds a complete snapshot.
+func writeSnapshot(path string, payload []byte, previous int) error {
+	tmpPath := path + snapshotTmpSuffix
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	if _, err := file.Write(encodeSnapshot(payload)); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to sync file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+
+	if err := rotateSnapshots(path, previous); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+// rotateSnapshots - the function that shifts older snapshots by one generation, the
+// oldest one is dropped and the latest one is linked as the first previous generation.
+func rotateSnapshots(path string, previous int) error {
+	if err := os.Remove(snapshotPath(path, max(previous, 1))); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove snapshot: %w", err)
+	}
+
+	for n := previous - 1; n >= 1; n-- {
+		err := os.Rename(snapshotPath(path, n), snapshotPath(path, n+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to rotate snapshot: %w", err)
+		}
+	}
+
+	if previous < 1 {
+		return nil
+	}
+
+	if err := os.Link(path, snapshotPath(path, 1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to keep snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// readSnapshot - the function that loads the latest valid snapshot with load, up to previous
+// older generations are tried. Missing generations and snapshots failing validation or loading
+// are skipped in favour of older ones, the latter are reported. The generation of the loaded
+// snapshot is returned, fs.ErrNotExist means there are no snapshots at all.
+func readSnapshot(
+	path string,
+	previous int,
+	load func(payload []byte) error,
+	onCorrupted func(path string, err error),
+) (int, error) {
+	found := false
+
+	for n := 0; n <= previous; n++ {
+		data, err := os.ReadFile(snapshotPath(path, n))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return n, fmt.Errorf("failed to read file: %w", err)
+		}
+		found = true
+
+		payload, err := decodeSnapshot(data)
+		if err == nil {
+			err = load(payload)
+		}
+		if err == nil {
+			return n, nil
+		}
+		onCorrupted(snapshotPath(path, n), err)
+	}
+
+	if !found {
+		return 0, fs.ErrNotExist
+	}
+
+	return 0, errSnapshotCorrupted
+}
+
+// syncDir - the function that makes the directory entries durable after rename.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open dir: %w", err)
+	}
+	defer dir.Close()
+
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync dir: %w", err)
+	}
+
+	return nil
+}
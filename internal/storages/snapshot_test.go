@@ -0,0 +1,145 @@
+package storages
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"metrix/internal/model"
+)
+
+func TestDecodeSnapshot(t *testing.T) {
+	payload := []byte(`{"PollCount":{"id":"PollCount","type":"counter","delta":1}}`)
+	encoded := encodeSnapshot(payload)
+
+	flipped := append([]byte{}, encoded...)
+	flipped[len(flipped)-2] ^= 0xff
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []byte
+		wantErr bool
+	}{
+		{name: "Test 1", data: encoded, want: payload},
+		{name: "Test 2", data: payload, want: payload},
+		{name: "Test 3", data: flipped, wantErr: true},
+		{name: "Test 4", data: encoded[:len(encoded)-5], wantErr: true},
+		{name: "Test 5", data: []byte("METRIX-SNAPSHOT 99 00000000 0\n"), wantErr: true},
+		{name: "Test 6", data: []byte("garbage"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeSnapshot(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeSnapshot() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errSnapshotCorrupted) {
+				t.Errorf("decodeSnapshot() error = %v, want %v", err, errSnapshotCorrupted)
+			}
+			if string(got) != string(tt.want) {
+				t.Errorf("decodeSnapshot() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteSnapshot_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	for _, payload := range []string{`{"1":{}}`, `{"2":{}}`, `{"3":{}}`, `{"4":{}}`} {
+		if err := writeSnapshot(path, []byte(payload), 2); err != nil {
+			t.Fatalf("writeSnapshot() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		n    int
+		want string
+	}{
+		{name: "Test 1", n: 0, want: `{"4":{}}`},
+		{name: "Test 2", n: 1, want: `{"3":{}}`},
+		{name: "Test 3", n: 2, want: `{"2":{}}`},
+		{name: "Test 4", n: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(snapshotPath(path, tt.n))
+			if tt.want == "" {
+				if !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("snapshot %d must not exist, error = %v", tt.n, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+
+			got, err := decodeSnapshot(data)
+			if err != nil || string(got) != tt.want {
+				t.Errorf("snapshot %d = %s, %v, want %s", tt.n, got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := os.Stat(path + snapshotTmpSuffix); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("temporary snapshot must be renamed, stat error = %v", err)
+	}
+}
+
+func TestMemoryStorage_RestoreFallback(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		corrupt []int
+		remove  []int
+		want    []string
+	}{
+		{name: "Test 1", want: []string{"Alloc", "Frees", "PollCount"}},
+		{name: "Test 2", corrupt: []int{0}, want: []string{"Frees", "PollCount"}},
+		{name: "Test 3", corrupt: []int{0, 1}, want: []string{"PollCount"}},
+		{name: "Test 4", corrupt: []int{0, 1, 2}},
+		{name: "Test 5", corrupt: []int{0}, remove: []int{1}, want: []string{"PollCount"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "metrics.json")
+
+			s := NewInMemoryStorage(ctx, path, 0, true, 0, 3)
+			for _, id := range []string{"PollCount", "Frees", "Alloc"} {
+				if _, err := s.UpsertMany(ctx, []model.Metric{counter(id, 1)}); err != nil {
+					t.Fatalf("UpsertMany() error = %v", err)
+				}
+				if err := s.compact(); err != nil {
+					t.Fatalf("compact() error = %v", err)
+				}
+			}
+
+			for _, n := range tt.corrupt {
+				if err := os.WriteFile(snapshotPath(path, n), []byte("METRIX-SNAPSHOT 1 0"), 0o600); err != nil {
+					t.Fatalf("WriteFile() error = %v", err)
+				}
+			}
+			for _, n := range tt.remove {
+				if err := os.Remove(snapshotPath(path, n)); err != nil {
+					t.Fatalf("Remove() error = %v", err)
+				}
+			}
+
+			restored := NewInMemoryStorage(ctx, path, 0, true, 0, 3)
+			for _, key := range tt.want {
+				if m, _ := restored.Read(ctx, key); m == nil {
+					t.Errorf("series %s is missing after restore", key)
+				}
+			}
+			if ids, _ := restored.ReadIDs(ctx); len(*ids) != len(tt.want) {
+				t.Errorf("restored %d series, want %d", len(*ids), len(tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,430 @@
+package storages
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"metrix/internal/model"
+)
+
+// walFileSuffix - the suffix of the write-ahead log kept next to the storage snapshot.
+const walFileSuffix = ".wal"
+
+// walRotatedSuffix - the suffix of the log segment being compacted into the snapshot.
+const walRotatedSuffix = ".1"
+
+// walHeaderSize - the size of the record header: payload length and its crc32 checksum.
+const walHeaderSize = 8
+
+// walMaxRecordSize - the upper bound of a record payload, larger lengths mean a corrupted log.
+const walMaxRecordSize = 64 << 20
+
+// ErrClosed - the error returned for changes made after the storage log has been closed.
+var ErrClosed = errors.New("storage is closed")
+
+// ErrBroken - the error returned once the storage log failed to persist records, every
+// further change is rejected. The failed records are not applied to memory, yet they may
+// have reached the disk and be replayed after restart.
+var ErrBroken = errors.New("storage log is broken")
+
+// walOp - the type of the logged storage change.
+type walOp string
+
+// walUpsert - the constant for the change that stores full metrics states.
+// walDelete - the constant for the change that removes a metric series.
+const (
+	walUpsert walOp = "upsert"
+	walDelete walOp = "delete"
+)
+
+// walEntry - the structure of a single logged storage change. Upserts carry full metric
+// states, so replaying an entry more than once gives the same storage.
+type walEntry struct {
+	Op      walOp          `json:"op"`
+	Metrics []model.Metric `json:"metrics,omitempty"`
+	Key     string         `json:"key,omitempty"`
+}
+
+// wal - the append-only log of storage changes. Records are framed with length and
+// crc32, so a torn tail left by a crash is detected and cut off on replay.
+// Concurrent commits are batched: one fsync makes every record appended before it durable.
+// A failed flush or fsync breaks the log, so no change is applied on top of a lost one.
+// Compaction rotates the log aside, so writers keep appending while the snapshot is taken.
+type wal struct {
+	mux     sync.Mutex
+	syncMux sync.Mutex
+	path    string
+	file    *os.File
+	writer  *bufio.Writer
+	seq     uint64
+	synced  atomic.Uint64
+	closed  bool
+	broken  bool
+}
+
+// openWAL - the builder function for wal, the log file is created when missing.
+func openWAL(path string) (*wal, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wal: %w", err)
+	}
+
+	return &wal{path: path, file: file, writer: bufio.NewWriter(file)}, nil
+}
+
+// replay - the method that applies every intact record in order, the segment left by an
+// interrupted compaction goes first. The log is truncated after the last intact record,
+// the number of applied records is returned.
+func (w *wal) replay(apply func(entry walEntry)) (int, error) {
+	if w == nil {
+		return 0, nil
+	}
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	count, err := replayRotated(w.path+walRotatedSuffix, apply)
+	if err != nil {
+		return count, err
+	}
+
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return count, fmt.Errorf("failed to seek wal: %w", err)
+	}
+
+	reader := bufio.NewReader(w.file)
+	header := make([]byte, walHeaderSize)
+
+	var offset int64
+	for {
+		entry, size, err := readRecord(reader, header)
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		}
+		if err != nil {
+			if err := w.file.Truncate(offset); err != nil {
+				return count, fmt.Errorf("failed to truncate wal: %w", err)
+			}
+			return count, fmt.Errorf("wal truncated after %d records: %w", count, err)
+		}
+
+		apply(entry)
+		offset += size
+		count++
+	}
+}
+
+// replayRotated - the function that applies the records of the rotated segment, a missing
+// segment means the last compaction completed. The segment was synced before rotation, so
+// its records are applied up to the first damaged one.
+func replayRotated(path string, apply func(entry walEntry)) (int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open rotated wal: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	header := make([]byte, walHeaderSize)
+
+	count := 0
+	for {
+		entry, _, err := readRecord(reader, header)
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		}
+		if err != nil {
+			return count, fmt.Errorf("rotated wal damaged after %d records: %w", count, err)
+		}
+
+		apply(entry)
+		count++
+	}
+}
+
+// readRecord - the function that reads a single record, io.EOF is returned only
+// at the clean end of the log.
+func readRecord(reader io.Reader, header []byte) (walEntry, int64, error) {
+	var entry walEntry
+
+	if _, err := io.ReadFull(reader, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return entry, 0, errors.New("torn record header")
+		}
+		return entry, 0, err
+	}
+
+	length := binary.LittleEndian.Uint32(header[:4])
+	checksum := binary.LittleEndian.Uint32(header[4:])
+	if length > walMaxRecordSize {
+		return entry, 0, fmt.Errorf("invalid record length %d", length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return entry, 0, errors.New("torn record payload")
+	}
+
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return entry, 0, errors.New("record checksum mismatch")
+	}
+
+	if err := json.Unmarshal(payload, &entry); err != nil {
+		return entry, 0, fmt.Errorf("failed to unmarshal record: %w", err)
+	}
+
+	return entry, int64(walHeaderSize) + int64(length), nil
+}
+
+// append - the method that buffers the record and returns its sequence number,
+// the record is durable once commit with this number returns.
+func (w *wal) append(entry walEntry) (uint64, error) {
+	if w == nil {
+		return 0, nil
+	}
+
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal record: %w", err)
+	}
+
+	header := make([]byte, walHeaderSize)
+	binary.LittleEndian.PutUint32(header[:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[4:], crc32.ChecksumIEEE(payload))
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if w.broken {
+		return 0, ErrBroken
+	}
+
+	if _, err := w.writer.Write(header); err != nil {
+		return 0, fmt.Errorf("failed to write record: %w", err)
+	}
+	if _, err := w.writer.Write(payload); err != nil {
+		return 0, fmt.Errorf("failed to write record: %w", err)
+	}
+	w.seq++
+
+	return w.seq, nil
+}
+
+// commit - the method that makes the records up to seq durable. Callers waiting for
+// an fsync in progress are served by the next one together.
+func (w *wal) commit(seq uint64) error {
+	if w == nil || w.synced.Load() >= seq {
+		return nil
+	}
+
+	w.syncMux.Lock()
+	defer w.syncMux.Unlock()
+
+	if w.synced.Load() >= seq {
+		return nil
+	}
+
+	w.mux.Lock()
+	if w.closed {
+		w.mux.Unlock()
+		return ErrClosed
+	}
+	if w.broken {
+		w.mux.Unlock()
+		return ErrBroken
+	}
+	target := w.seq
+	err := w.writer.Flush()
+	w.mux.Unlock()
+	if err != nil {
+		return w.fail(fmt.Errorf("failed to flush wal: %w", err))
+	}
+
+	if err := w.file.Sync(); err != nil {
+		return w.fail(fmt.Errorf("failed to sync wal: %w", err))
+	}
+	w.markSynced(target)
+
+	return nil
+}
+
+// fail - the method that marks the log broken after records failed to persist.
+func (w *wal) fail(err error) error {
+	w.mux.Lock()
+	w.broken = true
+	w.mux.Unlock()
+
+	return fmt.Errorf("%w: %w", ErrBroken, err)
+}
+
+// reset - the method that drops every record, must be called once the records are
+// covered by a durable snapshot.
+func (w *wal) reset() error {
+	if w == nil {
+		return nil
+	}
+
+	w.syncMux.Lock()
+	defer w.syncMux.Unlock()
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+
+	w.writer.Reset(w.file)
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate wal: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync wal: %w", err)
+	}
+	w.markSynced(w.seq)
+
+	return w.dropRotated()
+}
+
+// rotate - the method that moves the records appended so far to the rotated segment and
+// starts an empty log. When the segment of a failed compaction is still there, the records
+// are appended to it instead, so none of them is lost before the next snapshot.
+func (w *wal) rotate() error {
+	if w == nil {
+		return nil
+	}
+
+	w.syncMux.Lock()
+	defer w.syncMux.Unlock()
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush wal: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync wal: %w", err)
+	}
+	w.markSynced(w.seq)
+
+	rotatedPath := w.path + walRotatedSuffix
+	if _, err := os.Stat(rotatedPath); err == nil {
+		if err := appendFile(rotatedPath, w.file); err != nil {
+			return err
+		}
+		if err := w.file.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate wal: %w", err)
+		}
+		return nil
+	}
+
+	if err := os.Rename(w.path, rotatedPath); err != nil {
+		return fmt.Errorf("failed to rotate wal: %w", err)
+	}
+
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open wal: %w", err)
+	}
+	_ = w.file.Close()
+	w.file = file
+	w.writer.Reset(file)
+
+	return syncDir(filepath.Dir(w.path))
+}
+
+// appendFile - the function that durably appends the whole content of src to the file.
+func appendFile(path string, src *os.File) error {
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open rotated wal: %w", err)
+	}
+	defer dst.Close()
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek wal: %w", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		return fmt.Errorf("failed to copy wal: %w", err)
+	}
+	if err := dst.Sync(); err != nil {
+		return fmt.Errorf("failed to sync rotated wal: %w", err)
+	}
+
+	return nil
+}
+
+// dropRotated - the method that removes the rotated segment once a snapshot covering it
+// is durable.
+func (w *wal) dropRotated() error {
+	if w == nil {
+		return nil
+	}
+
+	err := os.Remove(w.path + walRotatedSuffix)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove rotated wal: %w", err)
+	}
+
+	return nil
+}
+
+// markSynced - the method that advances the durable sequence number.
+func (w *wal) markSynced(seq uint64) {
+	for {
+		cur := w.synced.Load()
+		if cur >= seq || w.synced.CompareAndSwap(cur, seq) {
+			return
+		}
+	}
+}
+
+// close - the method that flushes buffered records and closes the log, further changes
+// are rejected with ErrClosed.
+func (w *wal) close() error {
+	if w == nil {
+		return nil
+	}
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush wal: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync wal: %w", err)
+	}
+
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close wal: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,252 @@
+package storages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"metrix/internal/model"
+)
+
+func counter(id string, delta int64) model.Metric {
+	return model.Metric{ID: id, MType: model.CounterType, Delta: &delta}
+}
+
+func TestMemoryStorage_WALReplay(t *testing.T) {
+	// The context is never done, so background compaction does not race with cleanup.
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	s := NewInMemoryStorage(ctx, path, 0, true, 0, 1)
+
+	if _, err := s.UpsertMany(ctx, []model.Metric{counter("PollCount", 1), counter("Frees", 2)}); err != nil {
+		t.Fatalf("UpsertMany() error = %v", err)
+	}
+	if _, err := s.Update(ctx, &model.Metric{ID: "PollCount", MType: model.CounterType, Delta: new(int64)}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := s.Delete(ctx, "Frees"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := s.Create(ctx, &model.Metric{ID: fmt.Sprintf("Gauge%d", i), MType: model.CounterType}); err != nil {
+				t.Errorf("Create() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// No compaction happened, the snapshot is missing and the state comes from the log only.
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("snapshot must not exist before compaction, stat error = %v", err)
+	}
+
+	restored := NewInMemoryStorage(ctx, path, 0, true, 0, 1)
+	ids, _ := restored.ReadIDs(ctx)
+	if len(*ids) != 33 {
+		t.Errorf("restored %d series, want 33", len(*ids))
+	}
+
+	tests := []struct {
+		name  string
+		key   string
+		found bool
+		delta int64
+	}{
+		{name: "Test 1", key: "PollCount", found: true, delta: 0},
+		{name: "Test 2", key: "Frees", found: false},
+		{name: "Test 3", key: "Gauge31", found: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := restored.Read(ctx, tt.key)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if (m != nil) != tt.found {
+				t.Fatalf("Read() = %v, found %v", m, tt.found)
+			}
+			if m != nil && m.Delta != nil && *m.Delta != tt.delta {
+				t.Errorf("delta = %d, want %d", *m.Delta, tt.delta)
+			}
+		})
+	}
+}
+
+func TestMemoryStorage_WALTornTail(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	s := NewInMemoryStorage(ctx, path, 0, true, 0, 1)
+	for _, id := range []string{"A", "B"} {
+		if _, err := s.Create(ctx, &model.Metric{ID: id, MType: model.CounterType}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	info, err := os.Stat(path + walFileSuffix)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+
+	// A crash in the middle of a write leaves a partial record at the end of the log.
+	file, err := os.OpenFile(path+walFileSuffix, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	if _, err := file.Write([]byte{42, 0, 0, 0, 1, 2, 3, 4, '{'}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	file.Close()
+
+	restored := NewInMemoryStorage(ctx, path, 0, true, 0, 1)
+	ids, _ := restored.ReadIDs(ctx)
+	if len(*ids) != 2 {
+		t.Errorf("restored %d series, want 2", len(*ids))
+	}
+
+	after, err := os.Stat(path + walFileSuffix)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if after.Size() != info.Size() {
+		t.Errorf("wal size = %d, want torn tail cut to %d", after.Size(), info.Size())
+	}
+
+	if _, err := restored.Create(ctx, &model.Metric{ID: "C", MType: model.CounterType}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	ids, _ = NewInMemoryStorage(ctx, path, 0, true, 0, 1).ReadIDs(ctx)
+	if len(*ids) != 3 {
+		t.Errorf("restored %d series after append, want 3", len(*ids))
+	}
+}
+
+func TestMemoryStorage_Compact(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	s := NewInMemoryStorage(ctx, path, 0, true, 0, 1)
+	if _, err := s.UpsertMany(ctx, []model.Metric{counter("PollCount", 5)}); err != nil {
+		t.Fatalf("UpsertMany() error = %v", err)
+	}
+
+	if err := s.compact(); err != nil {
+		t.Fatalf("compact() error = %v", err)
+	}
+
+	info, err := os.Stat(path + walFileSuffix)
+	if err != nil || info.Size() != 0 {
+		t.Fatalf("wal after compaction = %v, %v, want empty", info, err)
+	}
+
+	if _, err := s.UpsertMany(ctx, []model.Metric{counter("Frees", 1)}); err != nil {
+		t.Fatalf("UpsertMany() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		restore bool
+		want    int
+	}{
+		{name: "Test 1", restore: true, want: 2},
+		{name: "Test 2", restore: false, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, _ := NewInMemoryStorage(ctx, path, 0, tt.restore, 0, 1).ReadIDs(ctx)
+			if len(*ids) != tt.want {
+				t.Errorf("restored %d series, want %d", len(*ids), tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStorage_WALClosed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := NewInMemoryStorage(ctx, filepath.Join(t.TempDir(), "metrics.json"), 0, false, 0, 1)
+	if _, err := s.UpsertMany(ctx, []model.Metric{counter("PollCount", 5)}); err != nil {
+		t.Fatalf("UpsertMany() error = %v", err)
+	}
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := s.UpsertMany(context.Background(), []model.Metric{counter("PollCount", 6)})
+		if errors.Is(err, ErrClosed) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("UpsertMany() after shutdown error = %v, want %v", err, ErrClosed)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMemoryStorage_WithoutWAL(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	if err := os.Mkdir(path+walFileSuffix, 0o700); err != nil {
+		t.Fatalf("failed to block wal: %v", err)
+	}
+
+	s := NewInMemoryStorage(ctx, path, 0, false, 0, 1)
+	if _, err := s.UpsertMany(ctx, []model.Metric{counter("PollCount", 5)}); err != nil {
+		t.Fatalf("UpsertMany() error = %v", err)
+	}
+	if err := s.compact(); err != nil {
+		t.Fatalf("compact() error = %v", err)
+	}
+
+	ids, _ := NewInMemoryStorage(ctx, path, 0, true, 0, 1).ReadIDs(ctx)
+	if len(*ids) != 1 {
+		t.Errorf("restored %d series, want 1", len(*ids))
+	}
+}
+
+func TestMemoryStorage_WALBroken(t *testing.T) {
+	ctx := context.Background()
+
+	s := NewInMemoryStorage(ctx, filepath.Join(t.TempDir(), "metrics.json"), 0, false, 0, 1)
+	if _, err := s.UpsertMany(ctx, []model.Metric{counter("PollCount", 5)}); err != nil {
+		t.Fatalf("UpsertMany() error = %v", err)
+	}
+
+	if err := s.wal.file.Close(); err != nil {
+		t.Fatalf("failed to break wal: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		metric model.Metric
+	}{
+		{name: "Test 1", metric: counter("PollCount", 6)},
+		{name: "Test 2", metric: counter("Frees", 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.UpsertMany(ctx, []model.Metric{tt.metric}); !errors.Is(err, ErrBroken) {
+				t.Errorf("UpsertMany() error = %v, want %v", err, ErrBroken)
+			}
+		})
+	}
+
+	if m, _ := s.Read(ctx, "PollCount"); m == nil || *m.Delta != 5 {
+		t.Errorf("Read() = %v, failed change must not be applied", m)
+	}
+	if m, _ := s.Read(ctx, "Frees"); m != nil {
+		t.Errorf("Read() = %v, failed change must not be applied", m)
+	}
+}
@@ -2,6 +2,7 @@ package validators
 
 import (
 	"io"
+	"net/url"
 
 	"metrix/internal/model"
 )
@@ -11,4 +12,6 @@ type MetricsValidator interface {
 	FromVars(vars map[string]string) (*model.Metric, error)
 	FromBody(body io.ReadCloser) (*model.Metric, error)
 	ManyFromBody(body io.ReadCloser) ([]*model.Metric, error)
+	LabelsFromQuery(values url.Values) (model.Labels, error)
+	RangeFromQuery(values url.Values) (*model.RangeQuery, error)
 }
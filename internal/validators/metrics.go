@@ -2,8 +2,13 @@ package validators
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
+	"math"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"metrix/internal/model"
 
@@ -77,6 +82,23 @@ func (v *MetricsValidatorImpl) FromVars(vars map[string]string) (*model.Metric,
 		metric.Delta = nil
 	}
 
+	if mtype == string(model.HistogramType) || mtype == string(model.SummaryType) {
+		metric.MType = model.MType(mtype)
+
+		val, ok := vars["value"]
+		if !ok {
+			return nil, NewParsingValueError("failed to retrieve value path param")
+		}
+
+		value, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return nil, NewParsingValueError("failed to parse value: %s", err)
+		}
+
+		metric.Value = &value
+		metric.Delta = nil
+	}
+
 	// Validate structure
 	err := v.validate.Struct(metric)
 	if err != nil {
@@ -95,16 +117,8 @@ func (v *MetricsValidatorImpl) FromBody(body io.ReadCloser) (*model.Metric, erro
 		return nil, NewParsingValueError("failed to parse metric json: %s", err)
 	}
 
-	if metric.MType != model.CounterType && metric.MType != model.GaugeType {
-		return nil, NewParsingValueError("failed to validate metric type: %s", err)
-	}
-
-	if metric.MType == model.CounterType {
-		metric.Value = nil
-	}
-
-	if metric.MType == model.GaugeType {
-		metric.Delta = nil
+	if err := normalizeMetric(metric); err != nil {
+		return nil, err
 	}
 
 	return metric, nil
@@ -120,18 +134,177 @@ func (v *MetricsValidatorImpl) ManyFromBody(body io.ReadCloser) ([]*model.Metric
 	}
 
 	for _, m := range metrics {
-		if m.MType != model.CounterType && m.MType != model.GaugeType {
-			return nil, NewParsingValueError("failed to validate metric type: %s", err)
+		if err := normalizeMetric(m); err != nil {
+			return nil, err
 		}
+	}
 
-		if m.MType == model.CounterType {
-			m.Value = nil
+	return metrics, nil
+}
+
+// labelParam - the query parameter carrying a label in name:value form, may be repeated.
+const labelParam = "label"
+
+// LabelsFromQuery - the function that parses labels from repeated query parameters,
+// e.g. ?label=host:h42&label=dc:eu.
+func (v *MetricsValidatorImpl) LabelsFromQuery(values url.Values) (model.Labels, error) {
+	raw := values[labelParam]
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	labels := model.Labels{}
+	for _, pair := range raw {
+		name, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, NewParsingValueError("label must be in name:value form, got %q", pair)
 		}
+		labels[name] = value
+	}
+
+	if err := labels.Validate(); err != nil {
+		return nil, NewParsingValueError("failed to validate labels: %s", err)
+	}
+
+	return labels, nil
+}
 
-		if m.MType == model.GaugeType {
-			m.Delta = nil
+// maxRangePoints - the limit of points a single range query may produce.
+const maxRangePoints = 11000
+
+// defaultRange - the time range used when "from" is not provided.
+const defaultRange = time.Hour
+
+// defaultRangePoints - the number of points used to derive the step when it is not provided.
+const defaultRangePoints = 100
+
+// minDefaultStep - the lowest derived step, so a range with equal bounds is still valid.
+const minDefaultStep = time.Second
+
+// RangeFromQuery - the function that parses range query parameters from url values.
+// Timestamps are accepted as RFC3339 or unix seconds, step as Go duration or seconds.
+func (v *MetricsValidatorImpl) RangeFromQuery(values url.Values) (*model.RangeQuery, error) {
+	query := &model.RangeQuery{
+		ID:          values.Get("id"),
+		To:          time.Now().UTC(),
+		Aggregation: model.AvgAggregation,
+	}
+
+	if raw := values.Get("to"); raw != "" {
+		to, err := parseTime(raw)
+		if err != nil {
+			return nil, NewParsingValueError("failed to parse to: %s", err)
 		}
+		query.To = to
 	}
 
-	return metrics, nil
+	query.From = query.To.Add(-defaultRange)
+	if raw := values.Get("from"); raw != "" {
+		from, err := parseTime(raw)
+		if err != nil {
+			return nil, NewParsingValueError("failed to parse from: %s", err)
+		}
+		query.From = from
+	}
+
+	query.Step = max(query.To.Sub(query.From)/defaultRangePoints, minDefaultStep)
+	if raw := values.Get("step"); raw != "" {
+		step, err := parseDuration(raw)
+		if err != nil {
+			return nil, NewParsingValueError("failed to parse step: %s", err)
+		}
+		query.Step = step
+	}
+
+	if raw := values.Get("agg"); raw != "" {
+		query.Aggregation = model.Aggregation(raw)
+	}
+
+	labels, err := v.LabelsFromQuery(values)
+	if err != nil {
+		return nil, err
+	}
+	query.Labels = labels
+
+	if err := v.validate.Struct(query); err != nil {
+		return nil, NewParsingValueError("failed to validate range query: %s", err)
+	}
+
+	if query.To.Sub(query.From)/query.Step > maxRangePoints {
+		return nil, NewParsingValueError("exceeded maximum resolution of %d points", maxRangePoints)
+	}
+
+	return query, nil
+}
+
+func parseTime(raw string) (time.Time, error) {
+	if seconds, err := strconv.ParseFloat(raw, 64); err == nil {
+		sec, frac := math.Modf(seconds)
+		return time.Unix(int64(sec), int64(frac*float64(time.Second))).UTC(), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse time %q: %w", raw, err)
+	}
+
+	return t.UTC(), nil
+}
+
+func parseDuration(raw string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(raw, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse duration %q: %w", raw, err)
+	}
+
+	return d, nil
+}
+
+func normalizeMetric(metric *model.Metric) error {
+	if err := metric.Labels.Validate(); err != nil {
+		return NewParsingValueError("failed to validate labels: %s", err)
+	}
+	if len(metric.Labels) == 0 {
+		metric.Labels = nil
+	}
+
+	switch metric.MType {
+	case model.CounterType:
+		metric.Value = nil
+		metric.Histogram = nil
+		metric.Summary = nil
+	case model.GaugeType:
+		metric.Delta = nil
+		metric.Histogram = nil
+		metric.Summary = nil
+	case model.HistogramType:
+		metric.Delta = nil
+		metric.Summary = nil
+		if metric.Histogram != nil {
+			if err := metric.Histogram.Validate(); err != nil {
+				return NewParsingValueError("failed to validate histogram: %s", err)
+			}
+		}
+	case model.SummaryType:
+		metric.Delta = nil
+		metric.Histogram = nil
+		if metric.Summary != nil {
+			if err := metric.Summary.Validate(); err != nil {
+				return NewParsingValueError("failed to validate summary: %s", err)
+			}
+		}
+		for _, q := range metric.Quantiles {
+			if q < 0 || q > 1 {
+				return NewParsingValueError("quantile must be in [0, 1], got %v", q)
+			}
+		}
+	default:
+		return NewParsingValueError("failed to validate metric type: %s", metric.MType)
+	}
+
+	return nil
 }
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.mtr_metrics ADD COLUMN IF NOT EXISTS summary jsonb NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.mtr_metrics DROP COLUMN IF EXISTS summary;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.mtr_samples (
+	id varchar NOT NULL,
+	mtype varchar NOT NULL,
+	ts timestamptz NOT NULL,
+	delta bigint NULL,
+	value double precision NULL
+);
+CREATE INDEX IF NOT EXISTS mtr_samples_id_ts_idx ON public.mtr_samples (id, ts);
+CREATE INDEX IF NOT EXISTS mtr_samples_ts_idx ON public.mtr_samples (ts);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.mtr_samples;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.mtr_metrics ADD COLUMN IF NOT EXISTS labels jsonb NOT NULL DEFAULT '{}';
+ALTER TABLE public.mtr_metrics ADD COLUMN IF NOT EXISTS skey varchar NULL;
+UPDATE public.mtr_metrics SET skey = id WHERE skey IS NULL;
+ALTER TABLE public.mtr_metrics ALTER COLUMN skey SET NOT NULL;
+ALTER TABLE public.mtr_metrics DROP CONSTRAINT IF EXISTS mtr_metrics_pk;
+ALTER TABLE public.mtr_metrics ADD CONSTRAINT mtr_metrics_pk PRIMARY KEY (skey, mtype);
+CREATE INDEX IF NOT EXISTS mtr_metrics_labels_idx ON public.mtr_metrics USING gin (labels);
+
+ALTER TABLE public.mtr_samples ADD COLUMN IF NOT EXISTS labels jsonb NOT NULL DEFAULT '{}';
+ALTER TABLE public.mtr_samples ADD COLUMN IF NOT EXISTS skey varchar NULL;
+UPDATE public.mtr_samples SET skey = id WHERE skey IS NULL;
+ALTER TABLE public.mtr_samples ALTER COLUMN skey SET NOT NULL;
+CREATE INDEX IF NOT EXISTS mtr_samples_skey_ts_idx ON public.mtr_samples (skey, ts);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.mtr_samples_skey_ts_idx;
+ALTER TABLE public.mtr_samples DROP COLUMN IF EXISTS skey;
+ALTER TABLE public.mtr_samples DROP COLUMN IF EXISTS labels;
+
+DROP INDEX IF EXISTS public.mtr_metrics_labels_idx;
+DELETE FROM public.mtr_metrics WHERE skey <> id;
+ALTER TABLE public.mtr_metrics DROP CONSTRAINT IF EXISTS mtr_metrics_pk;
+ALTER TABLE public.mtr_metrics ADD CONSTRAINT mtr_metrics_pk PRIMARY KEY (id, mtype);
+ALTER TABLE public.mtr_metrics DROP COLUMN IF EXISTS skey;
+ALTER TABLE public.mtr_metrics DROP COLUMN IF EXISTS labels;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.mtr_agents (
+	id varchar NOT NULL,
+	last_seen timestamptz NOT NULL,
+	CONSTRAINT mtr_agents_pk PRIMARY KEY (id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.mtr_agents;
+-- +goose StatementEnd
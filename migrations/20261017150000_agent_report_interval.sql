@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.mtr_agents ADD COLUMN IF NOT EXISTS report_interval bigint NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.mtr_agents DROP COLUMN IF EXISTS report_interval;
+-- +goose StatementEnd
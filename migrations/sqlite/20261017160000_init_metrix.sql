@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS mtr_metrics (
+	id text NOT NULL,
+	mtype text NOT NULL,
+	labels text NOT NULL DEFAULT '{}',
+	skey text NOT NULL,
+	delta integer NULL,
+	value real NULL,
+	histogram text NULL,
+	summary text NULL,
+	CONSTRAINT mtr_metrics_pk PRIMARY KEY (skey, mtype)
+);
+CREATE INDEX IF NOT EXISTS mtr_metrics_id_idx ON mtr_metrics (id);
+
+CREATE TABLE IF NOT EXISTS mtr_samples (
+	id text NOT NULL,
+	mtype text NOT NULL,
+	labels text NOT NULL DEFAULT '{}',
+	skey text NOT NULL,
+	ts datetime NOT NULL,
+	delta integer NULL,
+	value real NULL
+);
+CREATE INDEX IF NOT EXISTS mtr_samples_skey_ts_idx ON mtr_samples (skey, ts);
+CREATE INDEX IF NOT EXISTS mtr_samples_ts_idx ON mtr_samples (ts);
+
+CREATE TABLE IF NOT EXISTS mtr_agents (
+	id text NOT NULL,
+	last_seen datetime NOT NULL,
+	report_interval integer NOT NULL DEFAULT 0,
+	CONSTRAINT mtr_agents_pk PRIMARY KEY (id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS mtr_agents;
+DROP TABLE IF EXISTS mtr_samples;
+DROP TABLE IF EXISTS mtr_metrics;
+-- +goose StatementEnd
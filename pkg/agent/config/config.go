@@ -6,6 +6,9 @@ import (
 	"slices"
 	"time"
 
+	"metrix/internal/identity"
+	"metrix/pkg/crypto"
+
 	"github.com/caarlos0/env"
 	"github.com/pkg/errors"
 )
@@ -26,6 +29,15 @@ type Config struct {
 	CryptoKey        string        `env:"CRYPTO_KEY"                                       flag:"crypto-key"      flagShort:"i" flagDescription:"crypto key"`
 	ConfigFile       string        `env:"CONFIG"`
 	GRPCAddress      string        `env:"GRPC_ADDRESS"         envDefault:""               flag:"grpc-address"    flagShort:"g"  flagDescription:"grpc address"`
+	AgentID          string        `env:"AGENT_ID"             envDefault:""               flag:"agent-id"        flagShort:"n"  flagDescription:"agent identifier, metrics are not attributed without it"`
+	HTTPCACert       string        `env:"HTTP_CA_CERT"         envDefault:""               flag:"http-ca-cert"                   flagDescription:"ca verifying https server certificate, enables https"`
+	HTTPTLSCert      string        `env:"HTTP_TLS_CERT"        envDefault:""               flag:"http-tls-cert"                  flagDescription:"agent certificate for https client verification"`
+	HTTPTLSKey       string        `env:"HTTP_TLS_KEY"         envDefault:""               flag:"http-tls-key"                   flagDescription:"agent private key for https client verification"`
+	GRPCStreaming    bool          `env:"GRPC_STREAMING"       envDefault:"true"`
+	GRPCCACert       string        `env:"GRPC_CA_CERT"         envDefault:""               flag:"grpc-ca-cert"                   flagDescription:"ca verifying grpc server certificate, enables tls"`
+	GRPCTLSCert      string        `env:"GRPC_TLS_CERT"        envDefault:""               flag:"grpc-tls-cert"                  flagDescription:"agent certificate for mutual tls"`
+	GRPCTLSKey       string        `env:"GRPC_TLS_KEY"         envDefault:""               flag:"grpc-tls-key"                   flagDescription:"agent private key for mutual tls"`
+	GRPCServerName   string        `env:"GRPC_SERVER_NAME"     envDefault:""               flag:"grpc-server-name"               flagDescription:"grpc server name to verify, host of grpc address by default"`
 }
 
 // NewConfig - the builder function for Config.
@@ -83,5 +95,39 @@ func NewConfig() (*Config, error) {
 
 	parseFlags(cfg)
 
+	if (cfg.HTTPTLSCert == "") != (cfg.HTTPTLSKey == "") {
+		return nil, errors.New("http tls certificate and key must be set together")
+	}
+
+	if (cfg.GRPCTLSCert == "") != (cfg.GRPCTLSKey == "") {
+		return nil, errors.New("grpc tls certificate and key must be set together")
+	}
+
+	// with mutual tls the server identifies the agent by its certificate
+	if cfg.AgentID == "" && cfg.GRPCTLSCert != "" {
+		commonName, err := crypto.CertificateCommonName(cfg.GRPCTLSCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read agent certificate: %w", err)
+		}
+		cfg.AgentID = commonName
+	}
+
+	// without an agent id metrics are reported into the shared namespace
+	if cfg.AgentID != "" {
+		if err := identity.ValidateAgentID(cfg.AgentID); err != nil {
+			return nil, fmt.Errorf("failed to validate agent id: %w", err)
+		}
+	}
+
 	return cfg, nil
 }
+
+// HTTPUseTLS - the method that tells whether the http client uses https.
+func (c *Config) HTTPUseTLS() bool {
+	return c.HTTPCACert != "" || c.HTTPTLSCert != ""
+}
+
+// GRPCUseTLS - the method that tells whether the gRPC connection is secured with tls.
+func (c *Config) GRPCUseTLS() bool {
+	return c.GRPCCACert != "" || c.GRPCTLSCert != ""
+}
@@ -4,22 +4,29 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	pb "metrix/internal/grpcapi/proto/v1"
+	pbv2 "metrix/internal/grpcapi/proto/v2"
+	"metrix/internal/identity"
 	"metrix/pkg/crypto"
 	"metrix/pkg/logger"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 // Client - the structure that describes metric client concept.
@@ -41,6 +48,9 @@ func NewClient(
 	retryWaitTime time.Duration,
 	retryMaxWaitTime time.Duration,
 	encryption *crypto.Encryption,
+	agentID string,
+	reportInterval time.Duration,
+	tlsConfig *tls.Config,
 ) *Client {
 	c := &Client{
 		client:      resty.New(),
@@ -54,10 +64,16 @@ func NewClient(
 		SetHeader("Accept-Encoding", "gzip").
 		SetHeader("Content-Encoding", "gzip").
 		SetHeader("Content-Type", "application/json").
+		SetHeader(identity.AgentIDHeader, agentID).
+		SetHeader(identity.ReportIntervalHeader, identity.FormatReportInterval(reportInterval)).
 		SetRetryCount(retryCount).
 		SetRetryWaitTime(retryWaitTime).
 		SetRetryMaxWaitTime(retryMaxWaitTime)
 
+	if tlsConfig != nil {
+		c.client.SetTLSClientConfig(tlsConfig)
+	}
+
 	if c.useBatching {
 		canBatch, err := c.checkBatching(ctx)
 		if err != nil {
@@ -124,10 +140,7 @@ func (c Client) sendMetricBatch(
 	}
 
 	if c.signKey != "" {
-		h := hmac.New(sha256.New, []byte(c.signKey))
-		h.Write(body)
-		signature := h.Sum(nil)
-		req = req.SetHeader("HashSHA256", hex.EncodeToString(signature))
+		req = req.SetHeader(crypto.SignatureHeader, crypto.Sign(c.signKey, body))
 	}
 
 	resp, err := req.Post(c.baseURL + "/updates/")
@@ -194,10 +207,7 @@ func (c Client) sendMetric(
 		}
 
 		if c.signKey != "" {
-			h := hmac.New(sha256.New, []byte(c.signKey))
-			h.Write(body)
-			signature := h.Sum(nil)
-			req = req.SetHeader("HashSHA256", hex.EncodeToString(signature))
+			req = req.SetHeader(crypto.SignatureHeader, crypto.Sign(c.signKey, body))
 		}
 
 		resp, err := req.Post(c.baseURL + "/update/")
@@ -239,43 +249,192 @@ func (c *Client) SendMetrics(ctx context.Context, metrics []*Metric) error {
 	return nil
 }
 
+// GRPCClient - the structure that describes metric client over gRPC. Metrics are sent with
+// the lossless v2 API, over a long-lived stream when streaming is enabled. For servers
+// that do not support it the client falls back to v2 unary calls and then to the v1 API.
+// Requests are gzip compressed and signed when the sign key is set, signed clients
+// use unary calls only as stream messages cannot be signed.
 type GRPCClient struct {
-	conn   *grpc.ClientConn
-	client pb.MetricServiceClient
+	conn           *grpc.ClientConn
+	client         pb.MetricServiceClient
+	clientV2       pbv2.MetricServiceClient
+	agentID        string
+	reportInterval time.Duration
+
+	mux          *sync.Mutex
+	useStreaming bool
+	useV2        bool
+	stream       *metricsStream
+	seq          uint64
 }
 
-func NewGRPCClient(serverHost string) *GRPCClient {
-	conn, err := grpc.NewClient(serverHost, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// NewGRPCClient - the builder function for the GRPCClient.
+func NewGRPCClient(
+	serverHost string,
+	agentID string,
+	reportInterval time.Duration,
+	useStreaming bool,
+	tlsConfig *tls.Config,
+	signKey string,
+) *GRPCClient {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.UseCompressor(grpcgzip.Name)),
+	}
+	if signKey != "" {
+		opts = append(opts, grpc.WithUnaryInterceptor(signingInterceptor(signKey)))
+		if useStreaming {
+			logger.Warn(context.Background(), "metrics streaming is not signed, falling back to unary calls")
+			useStreaming = false
+		}
+	}
+
+	conn, err := grpc.NewClient(serverHost, opts...)
 	if err != nil {
 		logger.Warn(context.Background(), fmt.Sprintf("failed to build client: %s", err))
 	}
 
-	client := pb.NewMetricServiceClient(conn)
-
 	return &GRPCClient{
-		conn:   conn,
-		client: client,
+		conn:           conn,
+		client:         pb.NewMetricServiceClient(conn),
+		clientV2:       pbv2.NewMetricServiceClient(conn),
+		agentID:        agentID,
+		reportInterval: reportInterval,
+		mux:            &sync.Mutex{},
+		useStreaming:   useStreaming,
+		useV2:          true,
 	}
 }
 
 func (gc *GRPCClient) Close() {
+	gc.mux.Lock()
+	if gc.stream != nil {
+		gc.stream.close()
+		gc.stream = nil
+	}
+	gc.mux.Unlock()
+
 	if err := gc.conn.Close(); err != nil {
 		logger.Warn(context.Background(), fmt.Sprintf("failed to close client: %s", err))
 	}
 }
 
+// signingInterceptor - the client interceptor that signs request messages with HMAC-SHA256
+// and passes the signature in metadata.
+func signingInterceptor(signKey string) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		if msg, ok := req.(proto.Message); ok {
+			payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal request for signing")
+			}
+			ctx = metadata.AppendToOutgoingContext(
+				ctx,
+				crypto.SignatureMetadataKey, crypto.Sign(signKey, payload),
+			)
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// outgoingContext - the method that attaches agent identity to the outgoing metadata.
+func (gc *GRPCClient) outgoingContext(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(
+		ctx,
+		identity.AgentIDMetadataKey, gc.agentID,
+		identity.ReportIntervalMetadataKey, identity.FormatReportInterval(gc.reportInterval),
+	)
+}
+
+// SendMetrics - the method for sending metrics via gRPC.
 func (gc *GRPCClient) SendMetrics(ctx context.Context, metrics []*Metric) error {
-	request := pb.MetricsRequest{}
+	if gc.streaming() {
+		err := gc.sendStream(ctx, toProtoMetricsV2(metrics))
+		if status.Code(errors.Cause(err)) != codes.Unimplemented {
+			return err
+		}
+
+		logger.Warn(ctx, "server does not support metrics streaming, falling back to unary calls")
+		gc.mux.Lock()
+		gc.useStreaming = false
+		gc.mux.Unlock()
+	}
+
+	if gc.v2() {
+		resp, err := gc.clientV2.SetMetrics(
+			gc.outgoingContext(ctx),
+			&pbv2.MetricsRequest{Items: toProtoMetricsV2(metrics)},
+		)
+		if status.Code(err) != codes.Unimplemented {
+			if err != nil {
+				logger.Error(ctx, "failed to send metrics using GRPC", err)
+				return errors.Wrap(err, "failed to send metrics using GRPC")
+			}
+
+			logger.Info(ctx, fmt.Sprintf("grpc api response: %+v", resp))
+			return nil
+		}
+
+		logger.Warn(ctx, "server does not support v2 metrics API, falling back to v1")
+		gc.mux.Lock()
+		gc.useV2 = false
+		gc.mux.Unlock()
+	}
+
+	resp, err := gc.client.SetMetrics(
+		gc.outgoingContext(ctx),
+		&pb.MetricsRequest{Items: toProtoMetrics(metrics)},
+	)
+	if err != nil {
+		logger.Error(ctx, "failed to send metrics using GRPC", err)
+		return errors.Wrap(err, "failed to send metrics using GRPC")
+	}
+
+	logger.Info(ctx, fmt.Sprintf("grpc api response: %+v", resp))
+
+	return nil
+}
+
+func (gc *GRPCClient) streaming() bool {
+	gc.mux.Lock()
+	defer gc.mux.Unlock()
+
+	return gc.useStreaming
+}
+
+func (gc *GRPCClient) v2() bool {
+	gc.mux.Lock()
+	defer gc.mux.Unlock()
+
+	return gc.useV2
+}
+
+// toProtoMetrics - the function that converts metrics to the v1 API, values are sent as float32.
+func toProtoMetrics(metrics []*Metric) []*pb.Metric {
+	items := []*pb.Metric{}
 	for _, m := range metrics {
 		switch m.MType {
 		case "counter":
-			request.Items = append(request.Items, &pb.Metric{
+			items = append(items, &pb.Metric{
 				Id:    m.ID,
 				Mtype: pb.Metric_COUNTER,
 				Value: float32(*m.Delta),
 			})
 		case "gauge":
-			request.Items = append(request.Items, &pb.Metric{
+			items = append(items, &pb.Metric{
 				Id:    m.ID,
 				Mtype: pb.Metric_GAUGE,
 				Value: float32(*m.Value),
@@ -283,13 +442,28 @@ func (gc *GRPCClient) SendMetrics(ctx context.Context, metrics []*Metric) error
 		}
 	}
 
-	resp, err := gc.client.SetMetrics(ctx, &request)
-	if err != nil {
-		logger.Error(ctx, "failed to send metrics using GRPC", err)
-		return errors.Wrap(err, "failed to send metrics using GRPC")
-	}
+	return items
+}
 
-	logger.Info(ctx, fmt.Sprintf("grpc api response: %+v", resp))
+// toProtoMetricsV2 - the function that converts metrics to the v2 API.
+func toProtoMetricsV2(metrics []*Metric) []*pbv2.Metric {
+	items := []*pbv2.Metric{}
+	for _, m := range metrics {
+		switch m.MType {
+		case "counter":
+			items = append(items, &pbv2.Metric{
+				Id:      m.ID,
+				Mtype:   pbv2.Metric_COUNTER,
+				Payload: &pbv2.Metric_Delta{Delta: *m.Delta},
+			})
+		case "gauge":
+			items = append(items, &pbv2.Metric{
+				Id:      m.ID,
+				Mtype:   pbv2.Metric_GAUGE,
+				Payload: &pbv2.Metric_Value{Value: *m.Value},
+			})
+		}
+	}
 
-	return nil
+	return items
 }
@@ -0,0 +1,60 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"metrix/pkg/crypto"
+)
+
+func TestClient_SendMetricsTLS(t *testing.T) {
+	received := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	caPath := filepath.Join(t.TempDir(), "ca.crt")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: crypto.CertificateTitle, Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caPath, caPEM, 0o600); err != nil {
+		t.Fatalf("failed to write ca: %s", err)
+	}
+
+	delta := int64(1)
+	metrics := []*Metric{{ID: "PollCount", MType: "counter", Delta: &delta}}
+
+	tests := []struct {
+		name    string
+		caPath  string
+		wantErr bool
+	}{
+		{name: "Test 1", caPath: caPath, wantErr: false},
+		{name: "Test 2", caPath: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := crypto.ClientTLSConfig(tt.caPath, "", "", "")
+			if err != nil {
+				t.Fatalf("ClientTLSConfig() error = %s", err)
+			}
+
+			ctx := context.Background()
+			client := NewClient(ctx, server.URL, "", false, 0, 0, 0, nil, "agent-1", time.Second, tlsConfig)
+
+			if err := client.SendMetrics(ctx, metrics); (err != nil) != tt.wantErr {
+				t.Errorf("SendMetrics() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if received != 1 {
+		t.Errorf("server received %d requests, want 1", received)
+	}
+}
@@ -0,0 +1,131 @@
+package monitoring
+
+import (
+	"context"
+	"io"
+
+	pbv2 "metrix/internal/grpcapi/proto/v2"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// ackResult - the structure that delivers a batch acknowledgement or a stream failure.
+type ackResult struct {
+	ack *pbv2.MetricsAck
+	err error
+}
+
+// metricsStream - the structure that keeps an open StreamMetrics call and the batches
+// waiting for acknowledgement, waiters are guarded by the GRPCClient mutex.
+type metricsStream struct {
+	stream  grpc.BidiStreamingClient[pbv2.MetricsBatch, pbv2.MetricsAck]
+	cancel  context.CancelFunc
+	waiters map[uint64]chan ackResult
+}
+
+func (s *metricsStream) close() {
+	_ = s.stream.CloseSend()
+	s.cancel()
+}
+
+// openStream - the method that opens a new metrics stream, must be called under the mutex.
+func (gc *GRPCClient) openStream() (*metricsStream, error) {
+	ctx, cancel := context.WithCancel(gc.outgoingContext(context.Background()))
+
+	stream, err := gc.clientV2.StreamMetrics(ctx)
+	if err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "failed to open metrics stream")
+	}
+
+	s := &metricsStream{
+		stream:  stream,
+		cancel:  cancel,
+		waiters: make(map[uint64]chan ackResult),
+	}
+	go gc.receive(s)
+
+	return s, nil
+}
+
+// receive - the method that routes acknowledgements to waiting batches until the stream breaks,
+// then fails every pending batch and drops the stream so the next send reopens it.
+func (gc *GRPCClient) receive(s *metricsStream) {
+	for {
+		ack, err := s.stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("metrics stream closed by server")
+			}
+
+			gc.mux.Lock()
+			if gc.stream == s {
+				gc.stream = nil
+			}
+			for seq, ch := range s.waiters {
+				ch <- ackResult{err: err}
+				delete(s.waiters, seq)
+			}
+			gc.mux.Unlock()
+
+			s.cancel()
+			return
+		}
+
+		gc.mux.Lock()
+		ch, ok := s.waiters[ack.GetSeq()]
+		delete(s.waiters, ack.GetSeq())
+		gc.mux.Unlock()
+
+		if ok {
+			ch <- ackResult{ack: ack}
+		}
+	}
+}
+
+// sendStream - the method that sends metrics as a batch over the stream and waits for
+// the acknowledgement that they were persisted.
+func (gc *GRPCClient) sendStream(ctx context.Context, items []*pbv2.Metric) error {
+	gc.mux.Lock()
+	if gc.stream == nil {
+		s, err := gc.openStream()
+		if err != nil {
+			gc.mux.Unlock()
+			return err
+		}
+		gc.stream = s
+	}
+	s := gc.stream
+
+	gc.seq++
+	seq := gc.seq
+	result := make(chan ackResult, 1)
+	s.waiters[seq] = result
+
+	// io.EOF means the stream is broken, the actual status is delivered by receive
+	if err := s.stream.Send(&pbv2.MetricsBatch{Seq: seq, Items: items}); err != nil && !errors.Is(err, io.EOF) {
+		delete(s.waiters, seq)
+		gc.stream = nil
+		gc.mux.Unlock()
+		s.close()
+		return errors.Wrap(err, "failed to send metrics batch")
+	}
+	gc.mux.Unlock()
+
+	select {
+	case res := <-result:
+		if res.err != nil {
+			return errors.Wrap(res.err, "failed to stream metrics")
+		}
+		if !res.ack.GetStatus() {
+			return errors.Errorf("metrics batch %d was rejected: %s", seq, res.ack.GetMessage())
+		}
+		return nil
+	case <-ctx.Done():
+		gc.mux.Lock()
+		delete(s.waiters, seq)
+		gc.mux.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,157 @@
+package monitoring
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"metrix/internal/grpcapi/grpcservice"
+	pb "metrix/internal/grpcapi/proto/v1"
+	pbv2 "metrix/internal/grpcapi/proto/v2"
+	"metrix/internal/model"
+	"metrix/internal/storages"
+
+	"google.golang.org/grpc"
+)
+
+// unaryOnlyServer - the server that predates StreamMetrics and the v2 API.
+type unaryOnlyServer struct {
+	pb.UnimplementedMetricServiceServer
+	calls int
+}
+
+func (s *unaryOnlyServer) SetMetrics(context.Context, *pb.MetricsRequest) (*pb.MetricsResponse, error) {
+	s.calls++
+	return &pb.MetricsResponse{Status: true}, nil
+}
+
+func startServer(t *testing.T, register func(s *grpc.Server)) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	server := grpc.NewServer()
+	register(server)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+func TestGRPCClient_SendMetrics(t *testing.T) {
+	// above float32 precision, the v1 API would round it
+	delta := int64(1<<40 + 1)
+	value := float64(1.5)
+	metrics := []*Metric{
+		{ID: "PollCount", MType: "counter", Delta: &delta},
+		{ID: "Alloc", MType: "gauge", Value: &value},
+	}
+
+	t.Run("Streaming", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		repo := storages.NewInMemoryStorage(ctx, "", 0, false, 0, 1)
+		gs := grpcservice.NewGServiceServer(repo, storages.NewAgentMemoryStorage(), nil, nil)
+		address := startServer(t, func(s *grpc.Server) {
+			pb.RegisterMetricServiceServer(s, gs)
+			pbv2.RegisterMetricServiceServer(s, gs.V2())
+		})
+
+		client := NewGRPCClient(address, "agent-1", time.Second, true, nil, "")
+		defer client.Close()
+
+		for i := 0; i < 2; i++ {
+			if err := client.SendMetrics(ctx, metrics); err != nil {
+				t.Fatalf("SendMetrics() error = %v", err)
+			}
+		}
+
+		if !client.streaming() {
+			t.Errorf("client must keep streaming")
+		}
+
+		ids, err := repo.ReadIDs(ctx)
+		if err != nil || len(*ids) != 2 {
+			t.Errorf("ReadIDs() = %v, %v, want 2 series", ids, err)
+		}
+
+		metric, err := repo.Read(ctx, model.SeriesKey("PollCount", model.Labels{model.AgentLabel: "agent-1"}))
+		if err != nil || metric == nil {
+			t.Fatalf("Read() = %v, %v", metric, err)
+		}
+		if *metric.Delta != 2*delta {
+			t.Errorf("PollCount delta = %d, want %d", *metric.Delta, 2*delta)
+		}
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		srv := &unaryOnlyServer{}
+		address := startServer(t, func(s *grpc.Server) {
+			pb.RegisterMetricServiceServer(s, srv)
+		})
+		client := NewGRPCClient(address, "agent-1", time.Second, true, nil, "")
+		defer client.Close()
+
+		for i := 0; i < 2; i++ {
+			if err := client.SendMetrics(ctx, metrics); err != nil {
+				t.Fatalf("SendMetrics() error = %v", err)
+			}
+		}
+
+		if client.streaming() || client.v2() {
+			t.Errorf("client must fall back to v1 unary calls")
+		}
+		if srv.calls != 2 {
+			t.Errorf("SetMetrics calls = %d, want 2", srv.calls)
+		}
+	})
+}
+
+func TestGRPCClient_SendMetricsSigned(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	repo := storages.NewInMemoryStorage(ctx, "", 0, false, 0, 1)
+	gs := grpcservice.NewGServiceServer(repo, storages.NewAgentMemoryStorage(), nil, nil)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := gs.NewServer(nil, nil, "secret")
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	delta := int64(1)
+	metrics := []*Metric{{ID: "PollCount", MType: "counter", Delta: &delta}}
+
+	tests := []struct {
+		name    string
+		signKey string
+		wantErr bool
+	}{
+		{name: "Test 1", signKey: "secret", wantErr: false},
+		{name: "Test 2", signKey: "other", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewGRPCClient(listener.Addr().String(), "agent-1", time.Second, true, nil, tt.signKey)
+			defer client.Close()
+
+			if client.streaming() {
+				t.Errorf("signing client must not stream")
+			}
+			if err := client.SendMetrics(ctx, metrics); (err != nil) != tt.wantErr {
+				t.Errorf("SendMetrics() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
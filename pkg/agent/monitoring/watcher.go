@@ -2,6 +2,7 @@ package monitoring
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"strings"
 	"time"
@@ -98,16 +99,52 @@ func (w Watcher) Run(
 	ctx context.Context,
 	cfg *config.Config,
 ) {
+	reportInterval := time.Duration(cfg.ReportInterval * int64(time.Second))
+
 	if cfg.GRPCAddress != "" {
-		client := NewGRPCClient(cfg.GRPCAddress)
+		var tlsConfig *tls.Config
+		if cfg.GRPCUseTLS() {
+			var err error
+			tlsConfig, err = crypto.ClientTLSConfig(
+				cfg.GRPCCACert,
+				cfg.GRPCTLSCert,
+				cfg.GRPCTLSKey,
+				cfg.GRPCServerName,
+			)
+			if err != nil {
+				logger.Fatal(ctx, "failed to load grpc tls config", err)
+			}
+		}
+
+		client := NewGRPCClient(
+			cfg.GRPCAddress,
+			cfg.AgentID,
+			reportInterval,
+			cfg.GRPCStreaming,
+			tlsConfig,
+			cfg.SignKey,
+		)
 
 		for i := 1; i <= int(cfg.Goroutines); i++ {
 			go w.worker(ctx, i, client)
 		}
 	} else {
+		var tlsConfig *tls.Config
+		if cfg.HTTPUseTLS() {
+			var err error
+			tlsConfig, err = crypto.ClientTLSConfig(cfg.HTTPCACert, cfg.HTTPTLSCert, cfg.HTTPTLSKey, "")
+			if err != nil {
+				logger.Fatal(ctx, "failed to load http tls config", err)
+			}
+		}
+
 		address := cfg.Address
 		if !strings.HasPrefix(address, "http") {
-			address = "http://" + address
+			if tlsConfig != nil {
+				address = "https://" + address
+			} else {
+				address = "http://" + address
+			}
 		}
 
 		client := NewClient(
@@ -119,6 +156,9 @@ func (w Watcher) Run(
 			cfg.RetryWaitTime,
 			cfg.RetryMaxWaitTime,
 			w.encryption,
+			cfg.AgentID,
+			reportInterval,
+			tlsConfig,
 		)
 
 		for i := 1; i <= int(cfg.Goroutines); i++ {
@@ -127,7 +167,7 @@ func (w Watcher) Run(
 	}
 
 	go w.watch(ctx, time.Duration(cfg.PollInterval*int64(time.Second)))
-	go w.report(ctx, time.Duration(cfg.ReportInterval*int64(time.Second)))
+	go w.report(ctx, reportInterval)
 
 	<-ctx.Done()
 }
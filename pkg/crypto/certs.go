@@ -0,0 +1,158 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	CertificateTitle     = "CERTIFICATE"
+	PKCS8PrivateKeyTitle = "PRIVATE KEY"
+	serialNumberLength   = 128
+)
+
+// CertificateAuthority - the structure that issues certificates for local testing.
+type CertificateAuthority struct {
+	Cert *x509.Certificate
+	Key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// NewCertificateAuthority - the builder function for a self-signed CertificateAuthority.
+func NewCertificateAuthority(commonName string, validity time.Duration) (*CertificateAuthority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate ca key")
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create ca certificate")
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse ca certificate")
+	}
+
+	return &CertificateAuthority{Cert: cert, Key: key, der: der}, nil
+}
+
+// CertPEM - the method that encodes the CA certificate to PEM.
+func (ca *CertificateAuthority) CertPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: CertificateTitle, Bytes: ca.der})
+}
+
+// KeyPEM - the method that encodes the CA private key to PEM.
+func (ca *CertificateAuthority) KeyPEM() ([]byte, error) {
+	return encodeECKey(ca.Key)
+}
+
+// IssueServer - the method that issues a server certificate for the hosts,
+// which are either DNS names or IP addresses.
+func (ca *CertificateAuthority) IssueServer(
+	commonName string,
+	hosts []string,
+	validity time.Duration,
+) ([]byte, []byte, error) {
+	return ca.issue(commonName, hosts, x509.ExtKeyUsageServerAuth, validity)
+}
+
+// IssueClient - the method that issues a client certificate, the common name
+// identifies the client, e.g. the agent ID.
+func (ca *CertificateAuthority) IssueClient(
+	commonName string,
+	validity time.Duration,
+) ([]byte, []byte, error) {
+	return ca.issue(commonName, nil, x509.ExtKeyUsageClientAuth, validity)
+}
+
+func (ca *CertificateAuthority) issue(
+	commonName string,
+	hosts []string,
+	usage x509.ExtKeyUsage,
+	validity time.Duration,
+) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate key")
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, &key.PublicKey, ca.Key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create certificate")
+	}
+
+	keyPEM, err := encodeECKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: CertificateTitle, Bytes: der}), keyPEM, nil
+}
+
+func encodeECKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal private key")
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: PKCS8PrivateKeyTitle, Bytes: der}), nil
+}
+
+func newSerialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), serialNumberLength))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate serial number")
+	}
+
+	return serial, nil
+}
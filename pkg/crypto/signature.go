@@ -0,0 +1,27 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	// SignatureHeader - the http header carrying the HMAC-SHA256 signature of the body.
+	SignatureHeader = "HashSHA256"
+	// SignatureMetadataKey - the grpc metadata key carrying the HMAC-SHA256 signature of the request.
+	SignatureMetadataKey = "hashsha256"
+)
+
+// Sign - the function that calculates hex encoded HMAC-SHA256 signature of the payload.
+func Sign(key string, payload []byte) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write(payload)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifySignature - the function that checks the signature of the payload in constant time.
+func VerifySignature(key string, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(key, payload)), []byte(signature))
+}
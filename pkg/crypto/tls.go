@@ -0,0 +1,115 @@
+package crypto
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/pkg/errors"
+)
+
+// ServerTLSConfig - the function that builds TLS config for a server. When clientCAPath is set
+// clients must present a certificate signed by that CA (mutual TLS).
+func ServerTLSConfig(certPath string, keyPath string, clientCAPath string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load server certificate")
+	}
+
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if clientCAPath != "" {
+		pool, err := LoadCertPool(clientCAPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load client ca")
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
+
+// ClientTLSConfig - the function that builds TLS config for a client. Server certificates are
+// verified against caPath or the system pool when it is empty, the client certificate is
+// presented when certPath and keyPath are set.
+func ClientTLSConfig(
+	caPath string,
+	certPath string,
+	keyPath string,
+	serverName string,
+) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+
+	if caPath != "" {
+		pool, err := LoadCertPool(caPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load ca")
+		}
+		cfg.RootCAs = pool
+	}
+
+	if certPath != "" || keyPath != "" {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load client certificate")
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// ParseTLSVersion - the function that converts a version "1.2" or "1.3" to its tls constant,
+// deprecated versions 1.0 and 1.1 are rejected.
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+
+	return 0, errors.Errorf("unsupported tls version %q", version)
+}
+
+// LoadCertPool - the function that reads PEM encoded certificates into a pool.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	b, err := readFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read certificates")
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, errors.New("no certificates found in " + path)
+	}
+
+	return pool, nil
+}
+
+// CertificateCommonName - the function that reads the subject common name of a PEM certificate.
+func CertificateCommonName(certPath string) (string, error) {
+	b, err := readFile(certPath)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read certificate")
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != CertificateTitle {
+		return "", errors.New("failed to decode PEM block containing certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse certificate")
+	}
+
+	return cert.Subject.CommonName, nil
+}
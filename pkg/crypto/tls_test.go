@@ -0,0 +1,129 @@
+package crypto
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writePEM(t *testing.T, dir string, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %s", name, err)
+	}
+
+	return path
+}
+
+func handshake(serverCfg *tls.Config, clientCfg *tls.Config) error {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		err := tls.Server(serverConn, serverCfg).Handshake()
+		_ = serverConn.Close()
+		serverErr <- err
+	}()
+
+	_ = clientConn.SetDeadline(time.Now().Add(5 * time.Second))
+	client := tls.Client(clientConn, clientCfg)
+	if err := client.Handshake(); err != nil {
+		return err
+	}
+	// TLS 1.3 reports client certificate rejection after the client handshake
+	_, _ = client.Read(make([]byte, 1))
+
+	return <-serverErr
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	ca, err := NewCertificateAuthority("test ca", time.Hour)
+	if err != nil {
+		t.Fatalf("NewCertificateAuthority() error = %s", err)
+	}
+	caPath := writePEM(t, dir, "ca.crt", ca.CertPEM())
+
+	serverCert, serverKey, err := ca.IssueServer("localhost", []string{"localhost", "127.0.0.1"}, time.Hour)
+	if err != nil {
+		t.Fatalf("IssueServer() error = %s", err)
+	}
+	serverCertPath := writePEM(t, dir, "server.crt", serverCert)
+	serverKeyPath := writePEM(t, dir, "server.key", serverKey)
+
+	clientCert, clientKey, err := ca.IssueClient("agent-1", time.Hour)
+	if err != nil {
+		t.Fatalf("IssueClient() error = %s", err)
+	}
+	clientCertPath := writePEM(t, dir, "client.crt", clientCert)
+	clientKeyPath := writePEM(t, dir, "client.key", clientKey)
+
+	if cn, err := CertificateCommonName(clientCertPath); err != nil || cn != "agent-1" {
+		t.Errorf("CertificateCommonName() = %q, %v, want agent-1", cn, err)
+	}
+
+	tests := []struct {
+		name       string
+		clientCA   string
+		clientCert string
+		clientKey  string
+		serverName string
+		wantErr    bool
+	}{
+		{name: "Test 1", serverName: "localhost"},
+		{name: "Test 2", clientCA: caPath, clientCert: clientCertPath, clientKey: clientKeyPath, serverName: "localhost"},
+		{name: "Test 3", clientCA: caPath, serverName: "localhost", wantErr: true},
+		{name: "Test 4", serverName: "example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverCfg, err := ServerTLSConfig(serverCertPath, serverKeyPath, tt.clientCA)
+			if err != nil {
+				t.Fatalf("ServerTLSConfig() error = %s", err)
+			}
+
+			clientCfg, err := ClientTLSConfig(caPath, tt.clientCert, tt.clientKey, tt.serverName)
+			if err != nil {
+				t.Fatalf("ClientTLSConfig() error = %s", err)
+			}
+
+			if err := handshake(serverCfg, clientCfg); (err != nil) != tt.wantErr {
+				t.Errorf("handshake error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    uint16
+		wantErr bool
+	}{
+		{name: "Test 1", version: "1.2", want: tls.VersionTLS12},
+		{name: "Test 2", version: "1.3", want: tls.VersionTLS13},
+		{name: "Test 3", version: "1.0", wantErr: true},
+		{name: "Test 4", version: "1.1", wantErr: true},
+		{name: "Test 5", version: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTLSVersion(tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTLSVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTLSVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
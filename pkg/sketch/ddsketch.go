@@ -0,0 +1,273 @@
+// Module "sketch" implements mergeable streaming quantile sketches.
+package sketch
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultRelativeAccuracy - the relative accuracy used by NewDefault.
+const DefaultRelativeAccuracy = 0.01
+
+// DefaultMaxBins - the maximum number of bins per store, lowest bins are collapsed beyond it.
+// Decoded sketches may not declare more bins than that.
+const DefaultMaxBins = 2048
+
+// MinRelativeAccuracy - the lowest accepted relative accuracy, finer sketches would map
+// extreme values to bin indexes beyond int32.
+const MinRelativeAccuracy = 1e-6
+
+// ErrIncompatible - the error returned when sketches with different parameters are merged.
+var ErrIncompatible = errors.New("sketches have different relative accuracy")
+
+// DDSketch - the structure of a DDSketch: values are mapped into logarithmic bins, so any
+// quantile is returned with a relative error bounded by Alpha, and sketches built with the
+// same Alpha can be merged by adding their bin counts.
+type DDSketch struct {
+	Alpha    float64         `json:"alpha"`
+	MaxBins  int             `json:"max_bins"`
+	Positive map[int32]int64 `json:"positive,omitempty"`
+	Negative map[int32]int64 `json:"negative,omitempty"`
+	Zero     int64           `json:"zero,omitempty"`
+	Count    int64           `json:"count"`
+	Sum      float64         `json:"sum"`
+	Min      float64         `json:"min"`
+	Max      float64         `json:"max"`
+}
+
+// New - the builder function for DDSketch with given relative accuracy.
+func New(alpha float64) (*DDSketch, error) {
+	if alpha < MinRelativeAccuracy || alpha >= 1 {
+		return nil, fmt.Errorf("relative accuracy must be in [%v, 1), got %v", MinRelativeAccuracy, alpha)
+	}
+
+	return &DDSketch{
+		Alpha:    alpha,
+		MaxBins:  DefaultMaxBins,
+		Positive: map[int32]int64{},
+		Negative: map[int32]int64{},
+	}, nil
+}
+
+// NewDefault - the builder function for DDSketch with DefaultRelativeAccuracy.
+func NewDefault() *DDSketch {
+	s, _ := New(DefaultRelativeAccuracy)
+	return s
+}
+
+// Validate - the method that checks sketch consistency, e.g. after decoding. Accuracy and
+// the number of bins must be within the limits of New and DefaultMaxBins, so a decoded
+// sketch cannot grow without bound once merged.
+func (s *DDSketch) Validate() error {
+	if s.Alpha < MinRelativeAccuracy || s.Alpha >= 1 {
+		return fmt.Errorf("relative accuracy must be in [%v, 1), got %v", MinRelativeAccuracy, s.Alpha)
+	}
+
+	if s.MaxBins <= 0 || s.MaxBins > DefaultMaxBins {
+		return fmt.Errorf("max bins must be in [1, %d], got %d", DefaultMaxBins, s.MaxBins)
+	}
+
+	if len(s.Positive) > s.MaxBins || len(s.Negative) > s.MaxBins {
+		return fmt.Errorf("bins number exceeds max bins %d", s.MaxBins)
+	}
+
+	if s.Zero < 0 {
+		return fmt.Errorf("negative zero count %d", s.Zero)
+	}
+
+	total := s.Zero
+	for _, store := range []map[int32]int64{s.Positive, s.Negative} {
+		for k, c := range store {
+			if c < 0 {
+				return fmt.Errorf("negative count %d of bin %d", c, k)
+			}
+			total += c
+		}
+	}
+
+	if total != s.Count {
+		return fmt.Errorf("count %d does not match bins sum %d", s.Count, total)
+	}
+
+	return nil
+}
+
+func (s *DDSketch) gamma() float64 {
+	return (1 + s.Alpha) / (1 - s.Alpha)
+}
+
+func (s *DDSketch) index(v float64) int32 {
+	return int32(math.Ceil(math.Log(v) / math.Log(s.gamma())))
+}
+
+func (s *DDSketch) binValue(idx int32) float64 {
+	g := s.gamma()
+	return 2 * math.Pow(g, float64(idx)) / (g + 1)
+}
+
+// Add - the method that inserts a value into the sketch.
+func (s *DDSketch) Add(v float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return
+	}
+
+	if s.Positive == nil {
+		s.Positive = map[int32]int64{}
+	}
+	if s.Negative == nil {
+		s.Negative = map[int32]int64{}
+	}
+
+	switch {
+	case v > 0:
+		s.Positive[s.index(v)]++
+		s.collapse(s.Positive)
+	case v < 0:
+		s.Negative[s.index(-v)]++
+		s.collapse(s.Negative)
+	default:
+		s.Zero++
+	}
+
+	if s.Count == 0 || v < s.Min {
+		s.Min = v
+	}
+	if s.Count == 0 || v > s.Max {
+		s.Max = v
+	}
+	s.Count++
+	s.Sum += v
+}
+
+// collapse - merges the lowest bins of the store so it never exceeds MaxBins,
+// sacrificing accuracy of the lowest quantiles only.
+func (s *DDSketch) collapse(store map[int32]int64) {
+	if s.MaxBins <= 0 || len(store) <= s.MaxBins {
+		return
+	}
+
+	keys := sortedKeys(store)
+	excess := len(keys) - s.MaxBins
+	target := keys[excess]
+	for _, k := range keys[:excess] {
+		store[target] += store[k]
+		delete(store, k)
+	}
+}
+
+// Merge - the method that adds all values of other into s.
+func (s *DDSketch) Merge(other *DDSketch) error {
+	if other == nil || other.Count == 0 {
+		return nil
+	}
+
+	if s.Alpha != other.Alpha {
+		return ErrIncompatible
+	}
+
+	if s.Positive == nil {
+		s.Positive = map[int32]int64{}
+	}
+	if s.Negative == nil {
+		s.Negative = map[int32]int64{}
+	}
+
+	for k, c := range other.Positive {
+		s.Positive[k] += c
+	}
+	for k, c := range other.Negative {
+		s.Negative[k] += c
+	}
+	s.collapse(s.Positive)
+	s.collapse(s.Negative)
+
+	if s.Count == 0 || other.Min < s.Min {
+		s.Min = other.Min
+	}
+	if s.Count == 0 || other.Max > s.Max {
+		s.Max = other.Max
+	}
+	s.Zero += other.Zero
+	s.Count += other.Count
+	s.Sum += other.Sum
+
+	return nil
+}
+
+// Clone - the method that makes a deep copy of the sketch.
+func (s *DDSketch) Clone() *DDSketch {
+	if s == nil {
+		return nil
+	}
+
+	c := *s
+	c.Positive = make(map[int32]int64, len(s.Positive))
+	for k, v := range s.Positive {
+		c.Positive[k] = v
+	}
+	c.Negative = make(map[int32]int64, len(s.Negative))
+	for k, v := range s.Negative {
+		c.Negative[k] = v
+	}
+
+	return &c
+}
+
+// Quantile - the method that returns an estimation of the q-quantile, q in [0, 1].
+func (s *DDSketch) Quantile(q float64) (float64, error) {
+	if q < 0 || q > 1 {
+		return 0, fmt.Errorf("quantile must be in [0, 1], got %v", q)
+	}
+
+	if s.Count == 0 {
+		return 0, errors.New("sketch is empty")
+	}
+
+	if q == 0 {
+		return s.Min, nil
+	}
+	if q == 1 {
+		return s.Max, nil
+	}
+
+	rank := int64(q * float64(s.Count-1))
+
+	var seen int64
+	negKeys := sortedKeys(s.Negative)
+	for i := len(negKeys) - 1; i >= 0; i-- {
+		seen += s.Negative[negKeys[i]]
+		if seen > rank {
+			return s.clamp(-s.binValue(negKeys[i])), nil
+		}
+	}
+
+	seen += s.Zero
+	if seen > rank {
+		return 0, nil
+	}
+
+	for _, k := range sortedKeys(s.Positive) {
+		seen += s.Positive[k]
+		if seen > rank {
+			return s.clamp(s.binValue(k)), nil
+		}
+	}
+
+	return s.Max, nil
+}
+
+func (s *DDSketch) clamp(v float64) float64 {
+	return math.Max(s.Min, math.Min(s.Max, v))
+}
+
+func sortedKeys(store map[int32]int64) []int32 {
+	keys := make([]int32, 0, len(store))
+	for k := range store {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	return keys
+}
@@ -0,0 +1,129 @@
+package sketch
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDDSketch_Quantile(t *testing.T) {
+	s := NewDefault()
+	for i := 1; i <= 1000; i++ {
+		s.Add(float64(i))
+	}
+
+	tests := []struct {
+		name string
+		q    float64
+		want float64
+	}{
+		{name: "Test 1: Min", q: 0, want: 1},
+		{name: "Test 2: Median", q: 0.5, want: 500},
+		{name: "Test 3: P95", q: 0.95, want: 950},
+		{name: "Test 4: P99", q: 0.99, want: 990},
+		{name: "Test 5: Max", q: 1, want: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Quantile(tt.q)
+			if err != nil {
+				t.Errorf("DDSketch.Quantile() error = %v", err)
+				return
+			}
+			if math.Abs(got-tt.want) > tt.want*DefaultRelativeAccuracy+1 {
+				t.Errorf("DDSketch.Quantile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDDSketch_Merge(t *testing.T) {
+	a := NewDefault()
+	b := NewDefault()
+	whole := NewDefault()
+	for i := -500; i <= 500; i++ {
+		if i%2 == 0 {
+			a.Add(float64(i))
+		} else {
+			b.Add(float64(i))
+		}
+		whole.Add(float64(i))
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Errorf("DDSketch.Merge() error = %v", err)
+		return
+	}
+
+	if a.Count != whole.Count || a.Min != whole.Min || a.Max != whole.Max {
+		t.Errorf("DDSketch.Merge() = %+v, want %+v", a, whole)
+	}
+
+	for _, q := range []float64{0.1, 0.5, 0.9} {
+		got, _ := a.Quantile(q)
+		want, _ := whole.Quantile(q)
+		if got != want {
+			t.Errorf("DDSketch.Merge() quantile %v = %v, want %v", q, got, want)
+		}
+	}
+
+	other, _ := New(0.05)
+	other.Add(1)
+	if err := a.Merge(other); err == nil {
+		t.Errorf("DDSketch.Merge() expected incompatibility error")
+	}
+}
+
+func TestDDSketch_Collapse(t *testing.T) {
+	s := NewDefault()
+	s.MaxBins = 10
+	for i := 1; i <= 1000; i++ {
+		s.Add(float64(i))
+	}
+
+	if len(s.Positive) > s.MaxBins {
+		t.Errorf("DDSketch bins = %d, want at most %d", len(s.Positive), s.MaxBins)
+	}
+
+	if err := s.Validate(); err != nil {
+		t.Errorf("DDSketch.Validate() error = %v", err)
+	}
+
+	got, _ := s.Quantile(0.99)
+	if math.Abs(got-990) > 990*DefaultRelativeAccuracy+1 {
+		t.Errorf("DDSketch.Quantile() after collapse = %v, want %v", got, 990)
+	}
+}
+
+func TestDDSketch_Validate(t *testing.T) {
+	valid := NewDefault()
+	valid.Add(1)
+	valid.Add(-2)
+	valid.Add(0)
+
+	tests := []struct {
+		name    string
+		modify  func(s *DDSketch)
+		wantErr bool
+	}{
+		{name: "Test 1", modify: func(s *DDSketch) {}},
+		{name: "Test 2", modify: func(s *DDSketch) { s.Positive[5], s.Positive[6] = 5, -5 }, wantErr: true},
+		{name: "Test 3", modify: func(s *DDSketch) { s.MaxBins = 0 }, wantErr: true},
+		{name: "Test 4", modify: func(s *DDSketch) { s.MaxBins = DefaultMaxBins + 1 }, wantErr: true},
+		{name: "Test 5", modify: func(s *DDSketch) { s.Alpha = 1e-12 }, wantErr: true},
+		{name: "Test 6", modify: func(s *DDSketch) { s.Count++ }, wantErr: true},
+		{name: "Test 7", modify: func(s *DDSketch) {
+			s.MaxBins = 1
+			s.Positive[7] = 1
+			s.Count++
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid.Clone()
+			tt.modify(s)
+			if err := s.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("DDSketch.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}